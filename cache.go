@@ -1,36 +1,48 @@
-package main
+package shade
 
 import (
 	"crypto/sha256"
 	"encoding/gob"
 	"encoding/hex"
-	"log"
+	"fmt"
 	"os"
 	"path/filepath"
 )
 
-type CacheKey struct {
+// A cacheKey identifies a value in the on-disk cache in the .cache
+// directory.
+type cacheKey struct {
 	key string
 }
 
-func MakeCacheKey(args ...any) *CacheKey {
+// makeCacheKey returns the cache key of args, which must be encodable
+// with gob.
+func makeCacheKey(args ...any) (*cacheKey, error) {
 	h := sha256.New()
 
 	enc := gob.NewEncoder(h)
 	for _, arg := range args {
 		if err := enc.Encode(arg); err != nil {
-			panic("error encoding cache key: " + err.Error())
+			return nil, fmt.Errorf("encoding cache key: %w", err)
 		}
 	}
 
-	return &CacheKey{hex.EncodeToString(h.Sum(nil))}
+	return &cacheKey{hex.EncodeToString(h.Sum(nil))}, nil
 }
 
-func (ck *CacheKey) path() string {
+// String returns ck as a hex string. This is useful as a stable
+// identifier of the arguments of makeCacheKey.
+func (ck *cacheKey) String() string {
+	return ck.key
+}
+
+func (ck *cacheKey) path() string {
 	return filepath.Join(".cache", ck.key)
 }
 
-func (ck *CacheKey) Load(out any) bool {
+// load decodes ck's value from the cache into out and reports whether
+// it succeeded. It fails if there is no value or it can't be decoded.
+func (ck *cacheKey) load(out any) bool {
 	f, err := os.Open(ck.path())
 	if err != nil {
 		return false
@@ -43,19 +55,28 @@ func (ck *CacheKey) Load(out any) bool {
 	return true
 }
 
-func (ck *CacheKey) Save(val any) {
+// save writes val to the cache under ck.
+func (ck *cacheKey) save(val any) error {
 	if err := os.MkdirAll(".cache", 0777); err != nil {
-		log.Printf("error creating .cache: %s", err)
-		return
+		return fmt.Errorf("creating cache: %w", err)
 	}
 	f, err := os.Create(ck.path())
 	if err != nil {
-		log.Printf("error saving to cache: %s", err)
-		return
+		return fmt.Errorf("saving to cache: %w", err)
 	}
-	defer f.Close()
-	enc := gob.NewEncoder(f)
-	if err := enc.Encode(val); err != nil {
-		panic("error encoding cache value: " + err.Error())
+	if err := gob.NewEncoder(f).Encode(val); err != nil {
+		f.Close()
+		os.Remove(ck.path())
+		return fmt.Errorf("encoding cache value: %w", err)
 	}
+	if err := f.Close(); err != nil {
+		os.Remove(ck.path())
+		return fmt.Errorf("saving to cache: %w", err)
+	}
+	return nil
+}
+
+// remove deletes ck's value from the cache, if any.
+func (ck *cacheKey) remove() {
+	os.Remove(ck.path())
 }
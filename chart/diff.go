@@ -0,0 +1,252 @@
+package chart
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"time"
+
+	"github.com/aclements/shade"
+	"github.com/aclements/shade/solar"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/palette/moreland"
+	"gonum.org/v1/plot/plotter"
+)
+
+// SunChangeMap returns a heat map showing, for each day and time of
+// day, whether alt gains or loses sun compared to base. For example,
+// base and alt may be the results of two Scenarios.
+func SunChangeMap(base, alt *shade.IntensityOverTime, opts PlotOptions) (*plot.Plot, error) {
+	level := func(_ *shade.IntensityOverTime, p solar.SunLight) float64 { return shade.SunLevel(p) }
+	grid, err := diffGrid(base, alt, level)
+	if err != nil {
+		return nil, err
+	}
+
+	plt := newPlot(opts.theme())
+	plt.X.Tick.Marker = dayOfYearTicks{}
+	plt.X.Label.Text = "Day of year"
+	plt.Title.Text = "Change in sun exposure"
+	plt.Y.Tick.Marker = timeOfDayTicks{6}
+	plt.Y.Label.Text = "Time of day"
+
+	// The difference in sunLevel is one of these.
+	pal := sunChangePalette{
+		{"Lost sun", color.RGBA{0x31, 0x6b, 0xd6, 0xff}},
+		{"Lost to foliage", color.RGBA{0x7f, 0xa8, 0xe8, 0xff}},
+		{"Unchanged", color.RGBA{0x40, 0x40, 0x40, 0xff}},
+		{"Gained through foliage", color.RGBA{0xf5, 0xc0, 0x6b, 0xff}},
+		{"Gained sun", color.RGBA{0xf0, 0x8c, 0x00, 0xff}},
+	}
+	hm := plotter.NewHeatMap(grid, pal)
+	hm.Min, hm.Max = shade.LevelShade-shade.LevelDirect, shade.LevelDirect-shade.LevelShade
+	hm.NaN = color.Transparent
+	hm.Rasterized = true
+	plt.Add(hm)
+
+	thumbs := plotter.PaletteThumbnailers(pal)
+	for i, c := range pal {
+		plt.Legend.Add(c.label, thumbs[i])
+	}
+	return plt, nil
+}
+
+// IntensityDiffMap returns a heat map of the difference in sun intensity
+// (in W/m²) of b compared to a for each day and time of day. a and b
+// must have the same time increment, but may be for different test
+// points, scenarios, or years.
+func IntensityDiffMap(a, b *shade.IntensityOverTime, opts PlotOptions) (*ColorBarPlot, error) {
+	grid, err := diffGrid(a, b, (*shade.IntensityOverTime).Intensity)
+	if err != nil {
+		return nil, err
+	}
+
+	plt := newColorBarPlot(opts.theme())
+	plt.X.Tick.Marker = dayOfYearTicks{}
+	plt.X.Label.Text = "Day of year"
+	plt.Title.Text = "Sun exposure difference (W/m²)"
+	plt.Y.Tick.Marker = timeOfDayTicks{6}
+	plt.Y.Label.Text = "Time of day"
+
+	// Center the diverging palette on 0.
+	var max float64
+	for _, col := range grid.intensity {
+		for _, v := range col {
+			if math.Abs(v) > max {
+				max = math.Abs(v)
+			}
+		}
+	}
+	if max == 0 {
+		max = 1
+	}
+	pal := moreland.SmoothBlueRed().Palette(255)
+	hm := plotter.NewHeatMap(grid, pal)
+	hm.Min, hm.Max = -max, max
+	hm.NaN = color.Transparent
+	hm.Rasterized = true
+	plt.Add(hm)
+
+	plt.AddColorBar("Change (W/m²)", pal, -max, max, nil)
+	return plt, nil
+}
+
+// DurationDiff returns a plot of the daily change in sun duration of b
+// compared to a. Like IntensityDiffMap, days are aligned relative to
+// the start of a and b.
+func DurationDiff(a, b *shade.IntensityOverTime, opts PlotOptions) (*plot.Plot, error) {
+	if err := checkSameGrid(a, b); err != nil {
+		return nil, err
+	}
+	aDays, bDays := a.DailyTotals(), b.DailyTotals()
+	if len(bDays) < len(aDays) {
+		aDays = aDays[:len(bDays)]
+	}
+
+	var direct, filtered plotter.XYs
+	for i, at := range aDays {
+		bt := bDays[i]
+		day, _ := splitTime(at.Start)
+		x := float64(day.Unix())
+		direct = append(direct, plotter.XY{X: x, Y: (bt.Direct - at.Direct).Hours()})
+		filtered = append(filtered, plotter.XY{X: x, Y: (bt.Foliage - at.Foliage).Hours()})
+	}
+
+	plt := newPlot(opts.theme())
+	plt.X.Tick.Marker = dayOfYearTicks{}
+	plt.X.Label.Text = "Day of year"
+	plt.Title.Text = "Sun duration difference"
+	plt.Y.Label.Text = "Change (hours)"
+
+	zero := plotter.NewFunction(func(float64) float64 { return 0 })
+	zero.Color = color.Gray{0x80}
+	plt.Add(zero)
+	for _, s := range []struct {
+		label string
+		xys   plotter.XYs
+		color color.Color
+	}{
+		{"Direct sun", direct, color.RGBA{0xff, 0xc0, 0x00, 0xff}},
+		{"Foliage-filtered sun", filtered, color.RGBA{0x40, 0xc0, 0x40, 0xff}},
+	} {
+		l, err := plotter.NewLine(s.xys)
+		if err != nil {
+			return nil, err
+		}
+		l.Color = s.color
+		plt.Add(l)
+		plt.Legend.Add(s.label, l)
+	}
+	return plt, nil
+}
+
+type sunChangePalette []struct {
+	label string
+	color color.Color
+}
+
+func (p sunChangePalette) Colors() []color.Color {
+	out := make([]color.Color, len(p))
+	for i, c := range p {
+		out[i] = c.color
+	}
+	return out
+}
+
+// checkSameGrid returns an error if a and b cannot be laid out on the
+// same day by time-of-day grid. They may cover different days (for
+// example, different years), but must have the same time increment and
+// start at the same time of day.
+func checkSameGrid(a, b *shade.IntensityOverTime) error {
+	if len(a.Series()) == 0 || len(b.Series()) == 0 {
+		return fmt.Errorf("no sun data")
+	}
+	if a.Increment() != b.Increment() {
+		return fmt.Errorf("time increments differ: %s vs %s", a.Increment(), b.Increment())
+	}
+	_, aTOD := splitTime(a.Series()[0].T)
+	_, bTOD := splitTime(b.Series()[0].T)
+	if aTOD != bTOD {
+		return fmt.Errorf("start times of day differ: %s vs %s", aTOD, bTOD)
+	}
+	return nil
+}
+
+// diffGrid returns a grid of f(b, ·) - f(a, ·) laid out by day and time of
+// day like heatMap. Days are aligned relative to the first day of each
+// series and the grid covers only the days in both. Cells where the sun
+// is down in both series are NaN, and the rows are narrowed to times
+// when the sun is up in either series.
+func diffGrid(a, b *shade.IntensityOverTime, f func(*shade.IntensityOverTime, solar.SunLight) float64) (*sunIntensityGrid, error) {
+	if err := checkSameGrid(a, b); err != nil {
+		return nil, err
+	}
+	ag, bg := layoutGrid(a, f), layoutGrid(b, f)
+	if len(bg) < len(ag) {
+		ag = ag[:len(bg)]
+	}
+
+	rMin, rMax := -1, -1
+	for c, col := range ag {
+		for r := range col {
+			if math.IsNaN(col[r]) && math.IsNaN(bg[c][r]) {
+				continue
+			}
+			if rMin == -1 || r < rMin {
+				rMin = r
+			}
+			if r > rMax {
+				rMax = r
+			}
+		}
+	}
+	if rMin == -1 {
+		return nil, fmt.Errorf("sun is never up")
+	}
+
+	diff := make([][]float64, len(ag))
+	for c := range diff {
+		diff[c] = make([]float64, rMax-rMin+1)
+		for r := range diff[c] {
+			av, bv := ag[c][rMin+r], bg[c][rMin+r]
+			switch {
+			case math.IsNaN(av) && math.IsNaN(bv):
+				diff[c][r] = math.NaN()
+			case math.IsNaN(av):
+				diff[c][r] = bv
+			case math.IsNaN(bv):
+				diff[c][r] = -av
+			default:
+				diff[c][r] = bv - av
+			}
+		}
+	}
+	startDay, _ := splitTime(a.Series()[0].T)
+	return &sunIntensityGrid{diff, startDay, time.Duration(rMin) * a.Increment(), a.Increment()}, nil
+}
+
+// layoutGrid lays out f of each time step of o by day (column) and time
+// of day (row), like heatMap. Row 0 is midnight. Cells with no time
+// step or where the sun is down are NaN.
+func layoutGrid(o *shade.IntensityOverTime, f func(*shade.IntensityOverTime, solar.SunLight) float64) [][]float64 {
+	sunPos := o.Series()
+	startDay, _ := splitTime(sunPos[0].T)
+	rows := int(24 * time.Hour / o.Increment())
+	var grid [][]float64
+	for _, sun := range sunPos {
+		day, tod := splitTime(sun.T)
+		col := int(day.Sub(startDay) / (24 * time.Hour))
+		row := int(tod / o.Increment())
+		for col >= len(grid) {
+			c := make([]float64, rows)
+			for i := range c {
+				c[i] = math.NaN()
+			}
+			grid = append(grid, c)
+		}
+		if sun.Altitude >= 0 && row < rows {
+			grid[col][row] = f(o, sun)
+		}
+	}
+	return grid
+}
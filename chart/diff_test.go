@@ -0,0 +1,43 @@
+package chart
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aclements/shade"
+	"github.com/aclements/shade/solar"
+)
+
+func TestEmptySeries(t *testing.T) {
+	// IntensityOverPeriod returns an empty series if start >= end.
+	empty := shade.NewIntensityOverTime(nil, 0, time.Hour)
+	day := time.Date(2022, 6, 21, 0, 0, 0, 0, time.UTC)
+	var series []solar.SunLight
+	for h := 6; h < 18; h++ {
+		series = append(series, solar.SunLight{SunPos: solar.SunPos{T: day.Add(time.Duration(h) * time.Hour), Altitude: 45}, Light: 1})
+	}
+	full := shade.NewIntensityOverTime(series, 0, time.Hour)
+
+	opts := DefaultPlotOptions
+	if _, err := HeatMap(empty, nil, opts); err == nil {
+		t.Errorf("HeatMap: want error")
+	}
+	if _, err := ShadeDuration(empty, nil, opts); err == nil {
+		t.Errorf("ShadeDuration: want error")
+	}
+	if _, err := DLIPlot(empty, opts); err == nil {
+		t.Errorf("DLIPlot: want error")
+	}
+	for _, pair := range [][2]*shade.IntensityOverTime{{empty, full}, {full, empty}} {
+		a, b := pair[0], pair[1]
+		if _, err := SunChangeMap(a, b, opts); err == nil {
+			t.Errorf("SunChangeMap: want error")
+		}
+		if _, err := IntensityDiffMap(a, b, opts); err == nil {
+			t.Errorf("IntensityDiffMap: want error")
+		}
+		if _, err := DurationDiff(a, b, opts); err == nil {
+			t.Errorf("DurationDiff: want error")
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package chart
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/aclements/shade"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+)
+
+// DLIPlot returns a plot of the daily light integral of each day of o,
+// with reference lines at typical requirements of shade plants, part
+// sun plants, and full sun vegetables.
+func DLIPlot(o *shade.IntensityOverTime, opts PlotOptions) (*plot.Plot, error) {
+	if len(o.Series()) == 0 {
+		return nil, fmt.Errorf("no sun data")
+	}
+	plt := newPlot(opts.theme())
+	plt.X.Tick.Marker = dayOfYearTicks{}
+	plt.X.Label.Text = "Day of year"
+	plt.Title.Text = "Daily light integral"
+	plt.Y.Label.Text = "DLI (mol/m²/day)"
+	plt.Y.Min = 0
+
+	for _, ref := range []struct {
+		label string
+		dli   float64
+	}{
+		{"Shade plants", 6},
+		{"Part sun", 12},
+		{"Full sun vegetables", 22},
+	} {
+		dli := ref.dli
+		f := plotter.NewFunction(func(float64) float64 { return dli })
+		f.Color = color.Gray{0x80}
+		f.Dashes = []vg.Length{vg.Points(4), vg.Points(4)}
+		plt.Add(f)
+		labels, err := plotter.NewLabels(plotter.XYLabels{
+			XYs:    []plotter.XY{{X: float64(o.Series()[0].T.Unix()), Y: dli}},
+			Labels: []string{ref.label},
+		})
+		if err != nil {
+			return nil, err
+		}
+		labels.TextStyle[0].Color = color.Gray{0x80}
+		plt.Add(labels)
+	}
+
+	var xys plotter.XYs
+	for _, d := range o.DailyLightIntegral() {
+		day, _ := splitTime(d.Day)
+		xys = append(xys, plotter.XY{X: float64(day.Unix()), Y: d.DLI})
+	}
+	l, err := plotter.NewLine(xys)
+	if err != nil {
+		return nil, err
+	}
+	l.Color = color.RGBA{0x40, 0xc0, 0x40, 0xff}
+	plt.Add(l)
+	return plt, nil
+}
@@ -1,18 +1,54 @@
-package main
+package chart
 
 import (
+	"fmt"
 	"image/color"
 	"math"
 	"sort"
 	"time"
 
-	"gonum.org/v1/plot"
+	"github.com/aclements/shade"
+	"github.com/aclements/shade/solar"
 	"gonum.org/v1/plot/palette"
 	"gonum.org/v1/plot/plotter"
 )
 
-func (o *IntensityOverTime) HeatMap() *plot.Plot {
-	plt := o.newPlot()
+// HeatMapOptions control the color scale and overlays of HeatMap and
+// ShadeDuration. A nil *HeatMapOptions uses the defaults.
+type HeatMapOptions struct {
+	// Min and Max are the range of the color scale, in W/m². If Max is
+	// 0, the range goes up to the highest intensity in the data.
+	// Intensities below Min are drawn like Min, and intensities above
+	// Max are drawn in the palette's overflow color. Setting Max is
+	// useful for comparing heat maps on the same scale. Min below 0 is
+	// treated as 0, since the night color is below the scale.
+	Min, Max float64
+
+	// SunriseSunset and SolarNoon draw curves at the times of sunrise,
+	// sunset, and solar noon on each day. Since the Y axis of
+	// ShadeDuration isn't the time of day, it ignores these.
+	SunriseSunset, SolarNoon bool
+
+	// Seasons marks the equinoxes and solstices.
+	Seasons bool
+
+	// DST marks the days daylight saving time starts and ends.
+	DST bool
+
+	// Events are additional days to mark, such as the last frost.
+	Events []HeatMapEvent
+}
+
+// A HeatMapEvent is a labeled day marked on a heat map.
+type HeatMapEvent struct {
+	Label string
+	Date  time.Time
+}
+
+// HeatMap returns a heat map of the sun intensity on each day of o
+// (X) at each time of day (Y) in the colors of opts.
+func HeatMap(o *shade.IntensityOverTime, hmOpts *HeatMapOptions, opts PlotOptions) (*ColorBarPlot, error) {
+	plt := newColorBarPlot(opts.theme())
 	// The default plot.TimeTicks are terrible, so we compute our own.
 	xticks := dayOfYearTicks{}
 	plt.X.Tick.Marker = xticks
@@ -21,12 +57,17 @@ func (o *IntensityOverTime) HeatMap() *plot.Plot {
 	yticks := timeOfDayTicks{6}
 	plt.Y.Tick.Marker = yticks
 	plt.Y.Label.Text = "Time of day"
-	o.heatMap(plt, false)
-	return plt
+	if err := heatMap(o, plt, false, hmOpts, opts); err != nil {
+		return nil, err
+	}
+	return plt, nil
 }
 
-func (o *IntensityOverTime) ShadeDuration() *plot.Plot {
-	plt := o.newPlot()
+// ShadeDuration returns a heat map like HeatMap, but where the time
+// steps of each day are sorted from direct sun to darkness, so the
+// height of each color is the duration of that kind of sun.
+func ShadeDuration(o *shade.IntensityOverTime, hmOpts *HeatMapOptions, opts PlotOptions) (*ColorBarPlot, error) {
+	plt := newColorBarPlot(opts.theme())
 	xticks := dayOfYearTicks{}
 	plt.X.Tick.Marker = xticks
 	plt.X.Label.Text = "Day of year"
@@ -34,15 +75,27 @@ func (o *IntensityOverTime) ShadeDuration() *plot.Plot {
 	yticks := durationTicks{6}
 	plt.Y.Tick.Marker = yticks
 	plt.Y.Label.Text = "Duration"
-	o.heatMap(plt, true)
-	return plt
+	if err := heatMap(o, plt, true, hmOpts, opts); err != nil {
+		return nil, err
+	}
+	return plt, nil
 }
 
-func (o *IntensityOverTime) heatMap(plt *plot.Plot, sorted bool) {
+func heatMap(o *shade.IntensityOverTime, plt *ColorBarPlot, sorted bool, hmOpts *HeatMapOptions, opts PlotOptions) error {
+	if hmOpts == nil {
+		hmOpts = new(HeatMapOptions)
+	}
+	sunPos := o.Series()
+	if len(sunPos) == 0 {
+		return fmt.Errorf("no sun data")
+	}
+	// Night is drawn as -1, so keep it below the color scale.
+	min, max := math.Max(hmOpts.Min, 0), hmOpts.Max
+
 	type xy struct {
 		day       time.Time
 		tod       time.Duration
-		sun       SunLight
+		sun       solar.SunLight
 		intensity float64
 		col, row  int
 	}
@@ -53,16 +106,16 @@ func (o *IntensityOverTime) heatMap(plt *plot.Plot, sorted bool) {
 	// lit times.
 	var cMax, rMin, rMax int
 	rMax = -1
-	startDay, _ := splitTime(o.sunPos[0].T)
+	startDay, _ := splitTime(sunPos[0].T)
 	var startTOD time.Duration
-	xys := make([]xy, len(o.sunPos))
-	for i, sun := range o.sunPos {
+	xys := make([]xy, len(sunPos))
+	for i, sun := range sunPos {
 		xy := &xys[i]
 		xy.day, xy.tod = splitTime(sun.T)
 		xy.sun = sun
-		xy.intensity = sun.GlobalIntensity(o.elevationFeet)
+		xy.intensity = sun.GlobalIntensity(o.ElevationFeet())
 		xy.col = int(xy.day.Sub(startDay) / (24 * time.Hour))
-		xy.row = int(xy.tod / o.increment)
+		xy.row = int(xy.tod / o.Increment())
 		if xy.col > cMax {
 			cMax = xy.col
 		}
@@ -115,7 +168,7 @@ func (o *IntensityOverTime) heatMap(plt *plot.Plot, sorted bool) {
 			// Recompute row
 			for i := range day {
 				day[i].row = i
-				day[i].tod = o.increment * time.Duration(i)
+				day[i].tod = o.Increment() * time.Duration(i)
 				if i > rMax && day[i].intensity > 0 {
 					rMax = i
 				}
@@ -126,6 +179,7 @@ func (o *IntensityOverTime) heatMap(plt *plot.Plot, sorted bool) {
 	// Construct the grid.
 	var intensity [][]float64
 	var foliage [][]float64
+	dataMax := 0.0
 	for i := range xys {
 		xy := &xys[i]
 		for xy.col >= len(intensity) {
@@ -135,43 +189,59 @@ func (o *IntensityOverTime) heatMap(plt *plot.Plot, sorted bool) {
 		if xy.row < rMin || xy.row > rMax {
 			continue
 		}
+		v := math.Max(xy.intensity, min)
+		dataMax = math.Max(dataMax, v)
 		if xy.sun.Altitude < 0 {
+			// Use -1 when the sun isn't in the sky to render in the
+			// underflow color, which is the night color.
 			intensity[xy.col][xy.row-rMin] = -1
 			foliage[xy.col][xy.row-rMin] = math.NaN()
 		} else if xy.sun.Foliage {
 			intensity[xy.col][xy.row-rMin] = math.NaN()
-			foliage[xy.col][xy.row-rMin] = xy.intensity
+			foliage[xy.col][xy.row-rMin] = v
 		} else {
-			intensity[xy.col][xy.row-rMin] = xy.intensity
+			intensity[xy.col][xy.row-rMin] = v
 			foliage[xy.col][xy.row-rMin] = math.NaN()
 		}
 	}
-	grid := &sunIntensityGrid{intensity, startDay, startTOD, o.increment}
-	fGrid := &sunIntensityGrid{foliage, startDay, startTOD, o.increment}
+	grid := &sunIntensityGrid{intensity, startDay, startTOD, o.Increment()}
+	fGrid := &sunIntensityGrid{foliage, startDay, startTOD, o.Increment()}
+
+	if max == 0 {
+		max = dataMax
+	}
+	if max <= min {
+		max = min + 1
+	}
+	// Only show the overflow color if it's used.
+	pal := opts.palette()
+	var overflow color.Color
+	if dataMax > max {
+		overflow = pal.Overflow
+	}
 
 	// Finally, construct the heat map.
-	pal := palette.Heat(256, 1)
-	hm := plotter.NewHeatMap(grid, pal)
-	hm.Underflow = color.Black
-	hm.Overflow = color.White
+	hm := plotter.NewHeatMap(grid, pal.Direct)
+	hm.Min, hm.Max = min, max
+	hm.Underflow = pal.Night
+	hm.Overflow = pal.Overflow
 	hm.NaN = color.Transparent
 	// Even in vector formats, a rasterized heatmap makes more sense.
 	hm.Rasterized = true
 	plt.Add(hm)
 
-	fPal := foliagePalette{pal}
-	hm = plotter.NewHeatMap(fGrid, fPal)
+	hm = plotter.NewHeatMap(fGrid, pal.Foliage)
+	hm.Min, hm.Max = min, max
+	hm.Overflow = pal.Overflow
 	hm.NaN = color.Transparent
 	hm.Rasterized = true
 	plt.Add(hm)
 
-	// Construct a legend.
-	thumbs := plotter.PaletteThumbnailers(pal)
-	plt.Legend.Add("Full shade", thumbs[0])
-	//plt.Legend.Add("Partial shade", thumbs[len(thumbs)/2])
-	plt.Legend.Add("Direct sun", thumbs[len(thumbs)-1])
-	thumbs = plotter.PaletteThumbnailers(fPal)
-	plt.Legend.Add("Foliage shade", thumbs[0])
+	plt.AddColorBar("Direct sun\nW/m²", pal.Direct, min, max, overflow)
+	plt.AddColorBar("Foliage\nW/m²", pal.Foliage, min, max, overflow)
+
+	addOverlays(o, plt.Plot, sorted, hmOpts, opts.theme())
+	return nil
 }
 
 type sunIntensityGrid struct {
@@ -202,19 +272,6 @@ func (si *sunIntensityGrid) Y(r int) float64 {
 	return float64(t)
 }
 
-func (si *sunIntensityGrid) Min() float64 {
-	// We use -1 when the sun isn't in the sky to render in the underflow color.
-	return 0
-}
-
-func (si *sunIntensityGrid) Max() float64 {
-	// Solar radiation at sea level on the equator at noon.
-	//
-	// TODO: It's higher at higher elevations. Maybe I should just let
-	// gonum find the max?
-	return 1042
-}
-
 type foliagePalette struct {
 	p palette.Palette
 }
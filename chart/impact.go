@@ -0,0 +1,142 @@
+package chart
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"time"
+
+	"github.com/aclements/shade"
+	"github.com/aclements/shade/solar"
+	"gonum.org/v1/gonum/spatial/r3"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+	"gonum.org/v1/plot/vg/vgpdf"
+)
+
+// impactDates and impactHours are the standard times of shadow
+// diagrams for planning applications.
+var (
+	impactDates = []struct {
+		month time.Month
+		day   int
+	}{{time.March, 21}, {time.June, 21}, {time.December, 21}}
+	impactHours = []int{9, 12, 15}
+)
+
+// WriteShadowImpact writes a shadow impact assessment comparing the
+// existing and proposed scenarios of m to w as a PDF. A nil scenario
+// means m as is.
+//
+// The assessment has one page for each standard time used by planning
+// boards: 9 AM, noon, and 3 PM (in loc's standard time) on the equinox
+// and both solstices of year. Each page is a plan view like ShadowPlan
+// that highlights the areas newly shaded by the proposal, drawn in the
+// theme of opts.
+func WriteShadowImpact(w io.Writer, m *shade.ShadeModel, existing, proposed *shade.Scenario, year int, loc *time.Location, height, spacing float64, opts PlotOptions) error {
+	withScenario := func(s *shade.Scenario) (*shade.ShadeModel, error) {
+		if s == nil {
+			return m, nil
+		}
+		return m.WithScenario(s)
+	}
+	before, err := withScenario(existing)
+	if err != nil {
+		return err
+	}
+	after, err := withScenario(proposed)
+	if err != nil {
+		return err
+	}
+
+	c := pdfCanvas{vgpdf.New(11*vg.Inch, 8.5*vg.Inch)}
+	std := solar.StandardTime(year, loc)
+	for i, date := range impactDates {
+		for j, hour := range impactHours {
+			if i > 0 || j > 0 {
+				c.NextPage()
+			}
+			t := time.Date(year, date.month, date.day, hour, 0, 0, 0, std)
+			shadowImpactPlot(before, after, t, height, spacing, opts.theme()).Draw(draw.New(c))
+		}
+	}
+	if _, err := c.WriteTo(w); err != nil {
+		return fmt.Errorf("writing shadow impact: %w", err)
+	}
+	return nil
+}
+
+// Cell values of a shadow impact plan.
+const (
+	impactSun = iota
+	impactExisting
+	impactNew
+	impactRemoved
+)
+
+// shadowImpactPlot returns a plan view comparing the shade at time t
+// before and after a change to a model.
+func shadowImpactPlot(before, after *shade.ShadeModel, t time.Time, height, spacing float64, theme *Theme) *plot.Plot {
+	// Cover both models and their shadows.
+	min, max := before.Bounds()
+	min2, max2 := after.Bounds()
+	min, max = r3.Vec{X: math.Min(min.X, min2.X), Y: math.Min(min.Y, min2.Y), Z: math.Min(min.Z, min2.Z)}, r3.Vec{X: math.Max(max.X, max2.X), Y: math.Max(max.Y, max2.Y), Z: math.Max(max.Z, max2.Z)}
+	sun := solar.GetSunPos(t, before.Latitude(), before.Longitude())
+	grid := newPlanGrid(min, max, spacing, math.Max(shadowLength(before, sun.Altitude), shadowLength(after, sun.Altitude)))
+
+	beforeLayers, afterLayers := before.ActiveLayers(), after.ActiveLayers()
+	var newCells int
+	grid.fill(func(x, y float64) float64 {
+		pos := [3]float64{x, y, height}
+		b := shade.SunLevel(shade.TraceSun(beforeLayers, sun, pos)) != shade.LevelDirect
+		a := shade.SunLevel(shade.TraceSun(afterLayers, sun, pos)) != shade.LevelDirect
+		switch {
+		case a && b:
+			return impactExisting
+		case a:
+			return impactNew
+		case b:
+			return impactRemoved
+		}
+		return impactSun
+	})
+	for _, col := range grid.z {
+		for _, v := range col {
+			if v == impactNew {
+				newCells++
+			}
+		}
+	}
+
+	plt := newPlot(theme)
+	plt.Title.Text = fmt.Sprintf("Shadows at %s (new shade: %.0f square units)", t.Format("Jan 2 2006 3:04 PM MST"), float64(newCells)*spacing*spacing)
+	plt.X.Label.Text = "X (east)"
+	plt.Y.Label.Text = "Y (north)"
+
+	pal := sunChangePalette{
+		{"Sun", color.RGBA{0xff, 0xd0, 0x40, 0xff}},
+		{"Existing shade", color.RGBA{0x50, 0x50, 0x60, 0xff}},
+		{"New shade", color.RGBA{0xe0, 0x30, 0x30, 0xff}},
+		{"Shade removed", color.RGBA{0x40, 0xa0, 0xff, 0xff}},
+	}
+	hm := plotter.NewHeatMap(grid, pal)
+	hm.Min, hm.Max = impactSun, impactRemoved
+	hm.Rasterized = true
+	plt.Add(hm)
+
+	existing := footprint(before, grid, color.Black)
+	proposed := footprint(after, grid, color.White)
+	proposed.LineStyles[0].Dashes = []vg.Length{vg.Points(4), vg.Points(2)}
+	plt.Add(existing, proposed)
+
+	thumbs := plotter.PaletteThumbnailers(pal)
+	for i, c := range pal {
+		plt.Legend.Add(c.label, thumbs[i])
+	}
+	plt.Legend.Add("Existing buildings", &plotter.Line{LineStyle: existing.LineStyles[0]})
+	plt.Legend.Add("Proposed buildings", &plotter.Line{LineStyle: proposed.LineStyles[0]})
+	return plt
+}
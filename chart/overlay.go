@@ -0,0 +1,250 @@
+package chart
+
+import (
+	"image/color"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/aclements/shade"
+	"github.com/aclements/shade/solar"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// addOverlays adds the curves and markers selected by opts to plt, which
+// is a heat map of o in theme. If sorted, the Y axis of plt isn't the
+// time of day, so only day markers are added.
+func addOverlays(o *shade.IntensityOverTime, plt *plot.Plot, sorted bool, opts *HeatMapOptions, theme *Theme) {
+	sunPos := o.Series()
+	fg := theme.Foreground
+	ov := &heatMapOverlay{background: theme.Background}
+
+	if !sorted && (opts.SunriseSunset || opts.SolarNoon) {
+		rise, set, noon := sunTimes(o)
+		if opts.SunriseSunset {
+			style := draw.LineStyle{Color: fg, Width: vg.Points(1)}
+			ov.curves = append(ov.curves, overlayCurve{rise, style}, overlayCurve{set, style})
+			plt.Legend.Add("Sunrise/sunset", &plotter.Line{LineStyle: style})
+		}
+		if opts.SolarNoon {
+			style := draw.LineStyle{Color: fg, Width: vg.Points(1), Dashes: []vg.Length{vg.Points(4), vg.Points(2)}}
+			ov.curves = append(ov.curves, overlayCurve{noon, style})
+			plt.Legend.Add("Solar noon", &plotter.Line{LineStyle: style})
+		}
+	}
+
+	if len(sunPos) > 0 {
+		first, _ := splitTime(sunPos[0].T)
+		last, _ := splitTime(sunPos[len(sunPos)-1].T)
+		if opts.Seasons {
+			style := draw.LineStyle{Color: fg, Width: vg.Points(0.5), Dashes: []vg.Length{vg.Points(2), vg.Points(2)}}
+			for _, t := range seasonDates(first, last) {
+				label := "Solstice"
+				if t.Month() == time.March || t.Month() == time.September {
+					label = "Equinox"
+				}
+				ov.marks = append(ov.marks, dayMarker{t, label, style})
+			}
+		}
+		if opts.DST {
+			style := draw.LineStyle{Color: fg, Width: vg.Points(0.5), Dashes: []vg.Length{vg.Points(6), vg.Points(2)}}
+			for i := 1; i < len(sunPos); i++ {
+				_, off0 := sunPos[i-1].T.Zone()
+				_, off1 := sunPos[i].T.Zone()
+				if off0 == off1 {
+					continue
+				}
+				label := "DST ends"
+				if off1 > off0 {
+					label = "DST starts"
+				}
+				day, _ := splitTime(sunPos[i].T)
+				ov.marks = append(ov.marks, dayMarker{day, label, style})
+			}
+		}
+		style := draw.LineStyle{Color: fg, Width: vg.Points(1)}
+		for _, e := range opts.Events {
+			day, _ := splitTime(e.Date)
+			if !day.Before(first) && !day.After(last) {
+				ov.marks = append(ov.marks, dayMarker{day, e.Label, style})
+			}
+		}
+	}
+
+	if len(ov.curves) > 0 || len(ov.marks) > 0 {
+		sort.Slice(ov.marks, func(i, j int) bool { return ov.marks[i].day.Before(ov.marks[j].day) })
+		plt.Add(ov)
+		// The top of the heat map is usually the marker labels and the
+		// bottom right is usually night, so move the legend there.
+		plt.Legend.Top = false
+	}
+}
+
+// sunTimes returns the time of day of sunrise, sunset, and solar noon on
+// each day of o in heat map coordinates. Each curve is broken into
+// segments where it doesn't exist, such as polar night.
+func sunTimes(o *shade.IntensityOverTime) (rise, set, noon []plotter.XYs) {
+	sunPos := o.Series()
+	var riseC, setC, noonC curve
+	for start := 0; start < len(sunPos); {
+		day, _ := splitTime(sunPos[start].T)
+		end := start + 1
+		for end < len(sunPos) {
+			if d, _ := splitTime(sunPos[end].T); d != day {
+				break
+			}
+			end++
+		}
+		ps := sunPos[start:end]
+		start = end
+		x := float64(day.Unix())
+
+		// Interpolate the sun crossing the horizon between samples.
+		cross := func(p0, p1 solar.SunLight) float64 {
+			_, tod := splitTime(p0.T)
+			f := p0.Altitude / (p0.Altitude - p1.Altitude)
+			return float64(tod + time.Duration(f*float64(p1.T.Sub(p0.T))))
+		}
+		var riseY, setY float64
+		var haveRise, haveSet bool
+		top := 0
+		for i := range ps {
+			if ps[i].Altitude > ps[top].Altitude {
+				top = i
+			}
+			if i == 0 {
+				continue
+			}
+			if !haveRise && ps[i-1].Altitude < 0 && ps[i].Altitude >= 0 {
+				riseY, haveRise = cross(ps[i-1], ps[i]), true
+			}
+			if ps[i-1].Altitude >= 0 && ps[i].Altitude < 0 {
+				setY, haveSet = cross(ps[i-1], ps[i]), true
+			}
+		}
+		riseC.add(x, riseY, haveRise)
+		setC.add(x, setY, haveSet)
+
+		// Fit a parabola to the highest sample and its neighbors to
+		// find the peak between samples.
+		if top == 0 || top == len(ps)-1 || ps[top].Altitude < 0 {
+			noonC.add(0, 0, false)
+			continue
+		}
+		a0, a1, a2 := ps[top-1].Altitude, ps[top].Altitude, ps[top+1].Altitude
+		f := 0.0
+		if d := a0 - 2*a1 + a2; d != 0 {
+			f = 0.5 * (a0 - a2) / d
+		}
+		_, tod := splitTime(ps[top].T)
+		noonC.add(x, float64(tod+time.Duration(f*float64(o.Increment()))), true)
+	}
+	return riseC.segs, setC.segs, noonC.segs
+}
+
+// curve accumulates the segments of a line with gaps.
+type curve struct {
+	segs []plotter.XYs
+	gap  bool
+}
+
+// add adds the point (x, y) to the curve if ok, or otherwise breaks the
+// curve.
+func (c *curve) add(x, y float64, ok bool) {
+	if !ok {
+		c.gap = true
+		return
+	}
+	if c.gap || len(c.segs) == 0 {
+		c.segs = append(c.segs, nil)
+		c.gap = false
+	}
+	seg := &c.segs[len(c.segs)-1]
+	*seg = append(*seg, plotter.XY{X: x, Y: y})
+}
+
+// heatMapOverlay is a plot.Plotter that draws curves and day markers
+// over a heat map. Lines are drawn with a halo in the background color
+// so they stand out against any color of the heat map.
+//
+// It doesn't implement plot.DataRanger, so it never changes the range of
+// the heat map.
+type heatMapOverlay struct {
+	curves     []overlayCurve
+	marks      []dayMarker
+	background color.Color
+}
+
+type overlayCurve struct {
+	segs  []plotter.XYs
+	style draw.LineStyle
+}
+
+// A dayMarker is a labeled vertical line across a heat map.
+type dayMarker struct {
+	day   time.Time // At noon UTC, like splitTime
+	label string
+	style draw.LineStyle
+}
+
+func (ov *heatMapOverlay) Plot(c draw.Canvas, plt *plot.Plot) {
+	trX, trY := plt.Transforms(&c)
+	halo := func(style draw.LineStyle) draw.LineStyle {
+		style.Color = ov.background
+		style.Width += vg.Points(1.5)
+		style.Dashes = nil
+		return style
+	}
+
+	for _, cv := range ov.curves {
+		for _, seg := range cv.segs {
+			pts := make([]vg.Point, len(seg))
+			for i, p := range seg {
+				pts[i] = vg.Point{X: trX(p.X), Y: trY(p.Y)}
+			}
+			lines := c.ClipLinesXY(pts)
+			c.StrokeLines(halo(cv.style), lines...)
+			c.StrokeLines(cv.style, lines...)
+		}
+	}
+
+	// Label each marker near the top, on a background box so the label
+	// is readable over the heat map. If a label would overlap an
+	// earlier one, move it below that one.
+	label := plt.Legend.TextStyle
+	label.Rotation = math.Pi / 2
+	label.XAlign, label.YAlign = draw.XRight, draw.YBottom
+	pad := label.Font.Size / 4
+	var boxes []vg.Rectangle
+	for _, m := range ov.marks {
+		x := float64(m.day.Unix())
+		if x < plt.X.Min || x > plt.X.Max {
+			continue
+		}
+		X := trX(x)
+		line := []vg.Point{{X: X, Y: c.Min.Y}, {X: X, Y: c.Max.Y}}
+		c.StrokeLines(halo(m.style), line)
+		c.StrokeLines(m.style, line)
+
+		// The text is rotated, so its width is vertical.
+		w, h := label.Width(m.label), label.Height(m.label)
+		box := vg.Rectangle{
+			Min: vg.Point{X: X - 2*pad - h, Y: c.Max.Y - 3*pad - w},
+			Max: vg.Point{X: X - pad, Y: c.Max.Y - pad},
+		}
+		for _, prev := range boxes {
+			if box.Min.X < prev.Max.X && prev.Min.X < box.Max.X && box.Min.Y < prev.Max.Y && prev.Min.Y < box.Max.Y {
+				box.Max.Y = prev.Min.Y - pad
+				box.Min.Y = box.Max.Y - 2*pad - w
+			}
+		}
+		boxes = append(boxes, box)
+		c.SetColor(ov.background)
+		c.Fill(box.Path())
+		label.Color = m.style.Color
+		c.FillText(label, vg.Point{X: box.Max.X, Y: box.Max.Y - pad}, m.label)
+	}
+}
@@ -0,0 +1,44 @@
+package chart
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/aclements/shade"
+	"github.com/aclements/shade/solar"
+	"gonum.org/v1/plot/plotter"
+)
+
+func TestSunTimes(t *testing.T) {
+	// Two days where the sun's altitude is a parabola peaking at 12:15
+	// and crossing the horizon at 7:15 and 17:15.
+	var series []solar.SunLight
+	start := time.Date(2022, 5, 30, 0, 0, 0, 0, time.UTC)
+	for h := 0; h < 2*24; h++ {
+		d := float64(h%24) - 12.25
+		series = append(series, solar.SunLight{SunPos: solar.SunPos{T: start.Add(time.Duration(h) * time.Hour), Altitude: 50 - 2*d*d}})
+	}
+
+	rise, set, noon := sunTimes(shade.NewIntensityOverTime(series, 0, time.Hour))
+	for _, c := range []struct {
+		name string
+		segs []plotter.XYs
+		want time.Duration
+		tol  time.Duration
+	}{
+		{"sunrise", rise, 7*time.Hour + 15*time.Minute, 5 * time.Minute},
+		{"sunset", set, 17*time.Hour + 15*time.Minute, 5 * time.Minute},
+		{"noon", noon, 12*time.Hour + 15*time.Minute, time.Second},
+	} {
+		if len(c.segs) != 1 || len(c.segs[0]) != 2 {
+			t.Errorf("%s: got %v, want 1 segment of 2 days", c.name, c.segs)
+			continue
+		}
+		for _, p := range c.segs[0] {
+			if got := time.Duration(p.Y); math.Abs(float64(got-c.want)) > float64(c.tol) {
+				t.Errorf("%s: got %s, want %s", c.name, got, c.want)
+			}
+		}
+	}
+}
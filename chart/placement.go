@@ -0,0 +1,83 @@
+package chart
+
+import (
+	"fmt"
+
+	"github.com/aclements/shade"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// PlacementMap returns a plan view of the search region with each
+// candidate position colored by score. The top n placements are
+// labeled with their rank.
+func PlacementMap(s *shade.PlacementSearch, placements []shade.Placement, n int, opts PlotOptions) (*plot.Plot, error) {
+	theme := opts.theme()
+	plt := newPlot(theme)
+	plt.Title.Text = "Placements by " + s.Objective.Name
+	plt.X.Label.Text = "X (east)"
+	plt.Y.Label.Text = "Y (north)"
+
+	var outline plotter.XYs
+	for _, p := range s.Region {
+		outline = append(outline, plotter.XY{X: p[0], Y: p[1]})
+	}
+	poly, err := plotter.NewPolygon(outline)
+	if err != nil {
+		return nil, err
+	}
+	poly.Color = nil
+	poly.LineStyle.Color = theme.Foreground
+	plt.Add(poly)
+
+	if len(placements) == 0 {
+		return plt, nil
+	}
+	minScore, maxScore := placements[len(placements)-1].Score, placements[0].Score
+	pal := opts.palette().Direct.Colors()
+	var xys plotter.XYs
+	for _, p := range placements {
+		xys = append(xys, plotter.XY{X: p.Pos[0], Y: p.Pos[1]})
+	}
+	sc, err := plotter.NewScatter(xys)
+	if err != nil {
+		return nil, err
+	}
+	sc.GlyphStyleFunc = func(i int) draw.GlyphStyle {
+		frac := 1.0
+		if maxScore > minScore {
+			frac = (placements[i].Score - minScore) / (maxScore - minScore)
+		}
+		return draw.GlyphStyle{
+			Color:  pal[int(frac*float64(len(pal)-1))],
+			Radius: vg.Points(4),
+			Shape:  draw.CircleGlyph{},
+		}
+	}
+	plt.Add(sc)
+
+	if n > len(placements) {
+		n = len(placements)
+	}
+	var top plotter.XYLabels
+	for i, p := range placements[:n] {
+		top.XYs = append(top.XYs, plotter.XY{X: p.Pos[0], Y: p.Pos[1]})
+		label := fmt.Sprintf("#%d %.4g", i+1, p.Score)
+		if p.Tilt != 0 || p.Azimuth != 0 {
+			label += fmt.Sprintf(" (%g°/%g°)", p.Tilt, p.Azimuth)
+		}
+		top.Labels = append(top.Labels, label)
+	}
+	labels, err := plotter.NewLabels(top)
+	if err != nil {
+		return nil, err
+	}
+	for i := range labels.TextStyle {
+		labels.TextStyle[i].Color = theme.Foreground
+	}
+	labels.Offset = vg.Point{X: vg.Points(6)}
+	plt.Add(labels)
+	return plt, nil
+}
@@ -0,0 +1,257 @@
+package chart
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/aclements/shade"
+	"github.com/aclements/shade/geom"
+	"github.com/aclements/shade/solar"
+	"gonum.org/v1/gonum/spatial/r3"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// ShadowPlan returns a plan view of which parts of the model are in
+// shade at time t. It casts a ray toward the sun from every spacing
+// units across the model's footprint at the given height and colors
+// each point by whether it is in direct sun, foliage shade, or building
+// shade. The outline of the model's buildings is drawn on top.
+//
+// The plan extends past the model's footprint far enough to cover the
+// model's shadows.
+func ShadowPlan(m *shade.ShadeModel, t time.Time, height, spacing float64, opts PlotOptions) *plot.Plot {
+	layers := m.ActiveLayers()
+	sun := solar.GetSunPos(t, m.Latitude(), m.Longitude())
+	grid := modelPlanGrid(m, spacing, shadowLength(m, sun.Altitude))
+	grid.fill(func(x, y float64) float64 {
+		return shade.SunLevel(shade.TraceSun(layers, sun, [3]float64{x, y, height}))
+	})
+
+	plt := newPlot(opts.theme())
+	plt.Title.Text = "Shade at " + t.Format("Jan 2 2006 15:04 MST")
+	plt.X.Label.Text = "X (east)"
+	plt.Y.Label.Text = "Y (north)"
+
+	pal := sunChangePalette{
+		{"Building shade", color.RGBA{0x50, 0x50, 0x60, 0xff}},
+		{"Foliage shade", color.RGBA{0x40, 0x90, 0x40, 0xff}},
+		{"Direct sun", color.RGBA{0xff, 0xd0, 0x40, 0xff}},
+	}
+	hm := plotter.NewHeatMap(grid, pal)
+	hm.Min, hm.Max = shade.LevelShade, shade.LevelDirect
+	hm.Rasterized = true
+	plt.Add(hm)
+	plt.Add(footprint(m, grid, color.Black))
+
+	thumbs := plotter.PaletteThumbnailers(pal)
+	for i := len(pal) - 1; i >= 0; i-- {
+		plt.Legend.Add(pal[i].label, thumbs[i])
+	}
+	return plt
+}
+
+// footprint returns a contour plotter that outlines the buildings in
+// the model in color col, sampled on the same grid as g.
+func footprint(m *shade.ShadeModel, g *planGrid, col color.Color) *plotter.Contour {
+	_, max := m.Bounds()
+	var buildings []*shade.Layer
+	for _, l := range m.ActiveLayers() {
+		if !l.Foliage {
+			buildings = append(buildings, l)
+		}
+	}
+	fp := &planGrid{min: g.min, spacing: g.spacing, z: make([][]float64, len(g.z))}
+	for i := range fp.z {
+		fp.z[i] = make([]float64, len(g.z[i]))
+	}
+	fp.fill(func(x, y float64) float64 {
+		ray := geom.Ray{Origin: r3.Vec{X: x, Y: y, Z: max.Z + 1}, Dir: r3.Vec{Z: -1}}
+		for _, l := range buildings {
+			if _, hit := ray.IntersectMesh(l.Mesh); hit {
+				return 1
+			}
+		}
+		return 0
+	})
+	c := plotter.NewContour(fp, []float64{0.5}, nil)
+	c.LineStyles = []draw.LineStyle{{Color: col, Width: vg.Points(1)}}
+	return c
+}
+
+// shadowLength returns the length of the longest shadow cast by the
+// model when the sun is at altitude alt. Since shadows get arbitrarily
+// long as the sun approaches the horizon, this is limited to 4 times
+// the height of the model.
+func shadowLength(m *shade.ShadeModel, alt float64) float64 {
+	min, max := m.Bounds()
+	h := max.Z - min.Z
+	if alt <= 0 {
+		return 4 * h
+	}
+	return math.Min(h/math.Tan(alt*math.Pi/180), 4*h)
+}
+
+// planGrid is a regular grid of values over the X/Y plane of a model.
+// It implements plotter.GridXYZ.
+type planGrid struct {
+	min     [2]float64
+	spacing float64
+	z       [][]float64 // Indexed by column (X), then row (Y)
+}
+
+// modelPlanGrid returns a grid covering the footprint of the model's
+// active layers extended by margin on all sides, plus one cell so
+// outlines are closed.
+func modelPlanGrid(m *shade.ShadeModel, spacing, margin float64) *planGrid {
+	min, max := m.Bounds()
+	return newPlanGrid(min, max, spacing, margin)
+}
+
+// newPlanGrid returns a grid covering the X/Y extent of min and max
+// extended by margin on all sides, plus one cell.
+func newPlanGrid(min, max r3.Vec, spacing, margin float64) *planGrid {
+	margin += spacing
+	g := &planGrid{min: [2]float64{min.X - margin, min.Y - margin}, spacing: spacing}
+	cols := int(math.Ceil((max.X-min.X+2*margin)/spacing)) + 1
+	rows := int(math.Ceil((max.Y-min.Y+2*margin)/spacing)) + 1
+	g.z = make([][]float64, cols)
+	for i := range g.z {
+		g.z[i] = make([]float64, rows)
+	}
+	return g
+}
+
+// fill sets each cell of g to f of the cell's coordinates. It calls f
+// in parallel.
+func (g *planGrid) fill(f func(x, y float64) float64) {
+	var wg sync.WaitGroup
+	next := make(chan int)
+	for i := 0; i < runtime.GOMAXPROCS(0); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range next {
+				for r := range g.z[c] {
+					g.z[c][r] = f(g.X(c), g.Y(r))
+				}
+			}
+		}()
+	}
+	for c := range g.z {
+		next <- c
+	}
+	close(next)
+	wg.Wait()
+}
+
+func (g *planGrid) Dims() (c, r int) {
+	if len(g.z) == 0 {
+		return 0, 0
+	}
+	return len(g.z), len(g.z[0])
+}
+
+func (g *planGrid) Z(c, r int) float64 {
+	return g.z[c][r]
+}
+
+func (g *planGrid) X(c int) float64 {
+	return g.min[0] + float64(c)*g.spacing
+}
+
+func (g *planGrid) Y(r int) float64 {
+	return g.min[1] + float64(r)*g.spacing
+}
+
+// shadeHoursLevels are the contour levels of ShadeHoursPlan, in hours.
+var shadeHoursLevels = []float64{2, 4, 6}
+
+// ShadeHoursPlan returns a plan view of the average number of hours per
+// day that each point is in shade from start to end, sampling the sun
+// every increment. Like ShadowPlan, points are every spacing units at
+// the given height, and both building and foliage shade count as shade.
+// Contour lines mark 2, 4, and 6 hours of shade.
+//
+// For example, to apply the common winter solstice test, pass the
+// start and end of December 21.
+func ShadeHoursPlan(m *shade.ShadeModel, start, end time.Time, increment time.Duration, height, spacing float64, opts PlotOptions) *ColorBarPlot {
+	layers := m.ActiveLayers()
+	var suns []solar.SunPos
+	minNoon := 90.0
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		noon := 0.0
+		for t := day; t.Before(end) && t.Before(day.AddDate(0, 0, 1)); t = t.Add(increment) {
+			sun := solar.GetSunPos(t, m.Latitude(), m.Longitude())
+			if sun.Altitude < 0 {
+				continue
+			}
+			suns = append(suns, sun)
+			noon = math.Max(noon, sun.Altitude)
+		}
+		minNoon = math.Min(minNoon, noon)
+	}
+	days := math.Max(1, math.Round(end.Sub(start).Hours()/24))
+
+	// Cover shadows at noon on the day the sun is lowest.
+	grid := modelPlanGrid(m, spacing, shadowLength(m, minNoon))
+	grid.fill(func(x, y float64) float64 {
+		var shaded time.Duration
+		for _, sun := range suns {
+			if shade.SunLevel(shade.TraceSun(layers, sun, [3]float64{x, y, height})) != shade.LevelDirect {
+				shaded += increment
+			}
+		}
+		return shaded.Hours() / days
+	})
+
+	plt := newColorBarPlot(opts.theme())
+	plt.Title.Text = "Hours of shade per day"
+	if days > 1 {
+		plt.Title.Text += fmt.Sprintf(", %s to %s", start.Format("Jan 2"), end.AddDate(0, 0, -1).Format("Jan 2 2006"))
+	} else {
+		plt.Title.Text += ", " + start.Format("Jan 2 2006")
+	}
+	plt.X.Label.Text = "X (east)"
+	plt.Y.Label.Text = "Y (north)"
+
+	// Shade is dark.
+	pal := reversePalette(opts.palette().Direct)
+	hm := plotter.NewHeatMap(grid, pal)
+	hm.Min = 0
+	hm.Max = math.Max(hm.Max, 1)
+	hm.Rasterized = true
+	plt.Add(hm)
+
+	// Shade hours are multiples of increment, so cells often fall
+	// exactly on a level, which plotter.Contour handles poorly. Shift
+	// the contours down slightly so they enclose cells with at least
+	// each level of shade.
+	var levels []float64
+	for _, level := range shadeHoursLevels {
+		levels = append(levels, level-1e-6)
+	}
+	contours := plotter.NewContour(grid, levels, nil)
+	contours.LineStyles = nil
+	for i, level := range shadeHoursLevels {
+		style := draw.LineStyle{Color: color.RGBA{0x40, 0xa0, 0xff, 0xff}, Width: vg.Points(1.5)}
+		style.Dashes = []vg.Length{vg.Points(float64(2 * (len(shadeHoursLevels) - i))), vg.Points(2)}
+		if i == len(shadeHoursLevels)-1 {
+			style.Dashes = nil
+		}
+		contours.LineStyles = append(contours.LineStyles, style)
+		plt.Legend.Add(fmt.Sprintf("%g h", level), &plotter.Line{LineStyle: style})
+	}
+	plt.Add(contours)
+	// Buildings are always in shade, so outline them in white.
+	plt.Add(footprint(m, grid, color.White))
+
+	plt.AddColorBar("Shade (h)", pal, hm.Min, hm.Max, nil)
+	return plt
+}
@@ -0,0 +1,177 @@
+// Package chart plots the results of shade analyses.
+package chart
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	imgdraw "image/draw"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+	"gonum.org/v1/plot/vg/vgeps"
+	"gonum.org/v1/plot/vg/vgimg"
+	"gonum.org/v1/plot/vg/vgpdf"
+	"gonum.org/v1/plot/vg/vgsvg"
+)
+
+// A Drawer is a plot that can be drawn to a canvas, such as a
+// *plot.Plot or a *ColorBarPlot.
+type Drawer interface {
+	Draw(c draw.Canvas)
+}
+
+// PlotOptions control the colors of new plots and how WritePlot renders
+// them.
+type PlotOptions struct {
+	Width, Height vg.Length
+
+	// DPI is the resolution of raster formats. It is ignored by vector
+	// formats.
+	DPI int
+
+	// Theme and Palette are the colors of plots. If nil, they default
+	// to DarkTheme and HeatPalette.
+	Theme   *Theme
+	Palette *SunPalette
+}
+
+// DefaultPlotOptions renders plots at 20×15 cm and 150 DPI in DarkTheme
+// and HeatPalette.
+var DefaultPlotOptions = PlotOptions{
+	Width:   20 * vg.Centimeter,
+	Height:  15 * vg.Centimeter,
+	DPI:     150,
+	Theme:   DarkTheme,
+	Palette: HeatPalette,
+}
+
+// theme returns the theme of opts.
+func (opts PlotOptions) theme() *Theme {
+	if opts.Theme == nil {
+		return DarkTheme
+	}
+	return opts.Theme
+}
+
+// palette returns the palette of opts.
+func (opts PlotOptions) palette() *SunPalette {
+	if opts.Palette == nil {
+		return HeatPalette
+	}
+	return opts.Palette
+}
+
+// WritePlot writes plt to path. The format is chosen by the extension of
+// path, which must be one of .png, .jpg, .jpeg, .tif, .tiff, .svg, .pdf,
+// or .eps.
+func WritePlot(plt Drawer, path string, opts PlotOptions) error {
+	c, err := newPlotCanvas(filepath.Ext(path), opts)
+	if err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	plt.Draw(draw.New(c))
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := c.WriteTo(f); err != nil {
+		f.Close()
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// EncodePlot writes plt to w in the format with file extension ext, as
+// for WritePlot.
+func EncodePlot(w io.Writer, plt Drawer, ext string, opts PlotOptions) error {
+	c, err := newPlotCanvas(ext, opts)
+	if err != nil {
+		return err
+	}
+	plt.Draw(draw.New(c))
+	_, err = c.WriteTo(w)
+	return err
+}
+
+// newPlotCanvas returns a canvas for the format with file extension
+// ext.
+func newPlotCanvas(ext string, opts PlotOptions) (vg.CanvasWriterTo, error) {
+	w, h := opts.Width, opts.Height
+	switch strings.ToLower(ext) {
+	case ".png", ".jpg", ".jpeg", ".tif", ".tiff":
+		c := vgimg.NewWith(vgimg.UseWH(w, h), vgimg.UseDPI(opts.DPI))
+		switch strings.ToLower(ext) {
+		case ".png":
+			return vgimg.PngCanvas{Canvas: c}, nil
+		case ".jpg", ".jpeg":
+			return vgimg.JpegCanvas{Canvas: c}, nil
+		default:
+			return vgimg.TiffCanvas{Canvas: c}, nil
+		}
+	case ".svg":
+		return vgsvg.New(w, h), nil
+	case ".pdf":
+		return pdfCanvas{vgpdf.New(w, h)}, nil
+	case ".eps":
+		return epsCanvas{vgeps.New(w, h)}, nil
+	}
+	return nil, fmt.Errorf("unsupported plot format %q", ext)
+}
+
+// pdfCanvas is a vgpdf.Canvas that can draw rasterized heat maps.
+// plotter.HeatMap and plotter.ColorBar draw 16-bit images, which vgpdf
+// can't embed, so this converts images to 8 bits.
+type pdfCanvas struct {
+	*vgpdf.Canvas
+}
+
+func (c pdfCanvas) DrawImage(rect vg.Rectangle, img image.Image) {
+	switch img.(type) {
+	case *image.RGBA, *image.NRGBA, *image.Gray, *image.Paletted:
+	default:
+		img8 := image.NewRGBA(img.Bounds())
+		imgdraw.Draw(img8, img8.Bounds(), img, img.Bounds().Min, imgdraw.Src)
+		img = img8
+	}
+	c.Canvas.DrawImage(rect, img)
+}
+
+// epsCanvas is a vgeps.Canvas that can draw images. vgeps doesn't
+// support images at all, so this draws each pixel as a filled
+// rectangle. This is fine for heat maps, which have few pixels.
+type epsCanvas struct {
+	*vgeps.Canvas
+}
+
+func (c epsCanvas) DrawImage(rect vg.Rectangle, img image.Image) {
+	b := img.Bounds()
+	size := rect.Size()
+	pw, ph := size.X/vg.Length(b.Dx()), size.Y/vg.Length(b.Dy())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			col := img.At(x, y)
+			if _, _, _, a := col.RGBA(); a == 0 {
+				continue
+			}
+			// Image rows go down, but canvas Y goes up.
+			min := vg.Point{
+				X: rect.Min.X + vg.Length(x-b.Min.X)*pw,
+				Y: rect.Max.Y - vg.Length(y-b.Min.Y+1)*ph,
+			}
+			var p vg.Path
+			p.Move(min)
+			p.Line(vg.Point{X: min.X + pw, Y: min.Y})
+			p.Line(vg.Point{X: min.X + pw, Y: min.Y + ph})
+			p.Line(vg.Point{X: min.X, Y: min.Y + ph})
+			p.Close()
+			c.SetColor(color.NRGBAModel.Convert(col))
+			c.Fill(p)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package chart
+
+import (
+	"image/color"
+	"path/filepath"
+	"testing"
+
+	"gonum.org/v1/plot/palette"
+	"gonum.org/v1/plot/plotter"
+)
+
+func TestWritePlot(t *testing.T) {
+	// Heat maps and color bars are the hardest case because they draw
+	// images.
+	g := &planGrid{spacing: 1, z: [][]float64{{0, 1}, {2, 3}}}
+	plt := newColorBarPlot(DarkTheme)
+	hm := plotter.NewHeatMap(g, palette.Heat(16, 1))
+	hm.Rasterized = true
+	plt.Add(hm)
+	plt.AddColorBar("Z", palette.Heat(16, 1), 0, 3, color.White)
+
+	dir := t.TempDir()
+	for _, ext := range []string{".png", ".jpg", ".tiff", ".svg", ".pdf", ".eps"} {
+		if err := WritePlot(plt, filepath.Join(dir, "plot"+ext), DefaultPlotOptions); err != nil {
+			t.Errorf("%s: %v", ext, err)
+		}
+	}
+	if err := WritePlot(plt, filepath.Join(dir, "plot.bmp"), DefaultPlotOptions); err == nil {
+		t.Errorf(".bmp: expected error")
+	}
+}
+
+func TestNightColor(t *testing.T) {
+	// Night must not look like either no sun or full sun.
+	for _, pal := range SunPalettes {
+		night := color.RGBAModel.Convert(pal.Night)
+		for _, p := range []palette.Palette{pal.Direct, pal.Foliage} {
+			cs := p.Colors()
+			for _, c := range []color.Color{cs[0], cs[len(cs)-1]} {
+				if color.RGBAModel.Convert(c) == night {
+					t.Errorf("%s: night color %v is an end of the palette", pal.Name, night)
+				}
+			}
+		}
+	}
+}
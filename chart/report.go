@@ -0,0 +1,152 @@
+package chart
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"image/color"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/aclements/shade"
+	"gonum.org/v1/plot/palette"
+)
+
+//go:embed report.html
+var reportHTML string
+
+var reportTemplate = template.Must(template.New("report").Parse(reportHTML))
+
+// reportData is the data of the HTML report template.
+type reportData struct {
+	Title   string
+	Summary string
+	Total   reportTotals
+	Months  []reportTotals
+
+	// Style is the colors of the charts, from the Theme and
+	// SunPalette of the PlotOptions.
+	Style reportStyle
+
+	// Series is the subsampled sun light series.
+	Series reportSeries
+}
+
+type reportStyle struct {
+	Background string   `json:"background"`
+	Foreground string   `json:"foreground"`
+	Night      string   `json:"night"`
+	Direct     []string `json:"direct"`
+	Foliage    []string `json:"foliage"`
+}
+
+// reportTotals is a shade.SunTotals formatted for the report.
+type reportTotals struct {
+	Label            string  `json:"label"`
+	Days             int     `json:"days"`
+	Direct, Foliage  float64 `json:"-"` // Hours
+	DirectPerDay     float64 `json:"direct"`
+	FoliagePerDay    float64 `json:"foliage"`
+	Insolation       float64 `json:"-"` // kWh/m²
+	InsolationPerDay float64 `json:"insolation"`
+}
+
+// reportSeries is the sun light series in columns. Times are the local
+// wall clock time in Unix seconds, so the report shows the same times
+// as the plots regardless of the viewer's time zone. Values are rounded
+// to keep the report small.
+type reportSeries struct {
+	Step      int64     `json:"step"` // Seconds
+	Local     []int64   `json:"local"`
+	Altitude  []float64 `json:"altitude"`
+	Azimuth   []float64 `json:"azimuth"`
+	Light     []float64 `json:"light"`
+	Foliage   []bool    `json:"foliage"`
+	Intensity []float64 `json:"intensity"` // W/m²
+}
+
+// WriteHTMLReport writes an interactive report of o to w as a single HTML
+// file. The report has a heat map and sun duration chart like HeatMap
+// and ShadeDuration, a chart of sun hours per month, and a table of
+// monthly totals. Hovering over the charts shows the exact values of
+// each time step. The report doesn't load any network resources, so it
+// can be viewed offline, but this means it embeds the whole series: a
+// year at one-minute steps is about 20 MB. The charts use the colors of
+// opts.
+func WriteHTMLReport(w io.Writer, o *shade.IntensityOverTime, title string, opts PlotOptions) error {
+	sunPos := o.Series()
+	if len(sunPos) == 0 {
+		return fmt.Errorf("no sun data to report")
+	}
+
+	theme, pal := opts.theme(), opts.palette()
+	data := reportData{
+		Title: title,
+		Total: newReportTotals(o.Totals()),
+		Style: reportStyle{
+			Background: hexColor(theme.Background),
+			Foreground: hexColor(theme.Foreground),
+			Night:      hexColor(pal.Night),
+			Direct:     hexPalette(pal.Direct, 64),
+			Foliage:    hexPalette(pal.Foliage, 64),
+		},
+	}
+	data.Total.Label = "Total"
+	for _, t := range o.MonthlyTotals() {
+		data.Months = append(data.Months, newReportTotals(t))
+	}
+	var summary strings.Builder
+	if err := o.ClassifySun(shade.DefaultGrowingSeason).WriteReport(&summary, true); err != nil {
+		return err
+	}
+	data.Summary = summary.String()
+
+	// Include every time step so the tooltips show exact values. The
+	// charts draw a pixel per time step and let the canvas scale them
+	// down.
+	s := &data.Series
+	s.Step = int64(o.Increment().Seconds())
+	round := func(v, scale float64) float64 { return math.Round(v*scale) / scale }
+	for _, sun := range sunPos {
+		_, offset := sun.T.Zone()
+		s.Local = append(s.Local, sun.T.Unix()+int64(offset))
+		s.Altitude = append(s.Altitude, round(sun.Altitude, 10))
+		s.Azimuth = append(s.Azimuth, round(sun.Azimuth, 10))
+		s.Light = append(s.Light, round(sun.Light, 100))
+		s.Foliage = append(s.Foliage, sun.Foliage)
+		s.Intensity = append(s.Intensity, math.Round(o.Intensity(sun)))
+	}
+
+	return reportTemplate.Execute(w, &data)
+}
+
+func newReportTotals(t shade.SunTotals) reportTotals {
+	days := math.Max(1, float64(t.Days))
+	return reportTotals{
+		Label:            t.Label(),
+		Days:             t.Days,
+		Direct:           t.Direct.Hours(),
+		Foliage:          t.Foliage.Hours(),
+		DirectPerDay:     t.DirectPerDay().Hours(),
+		FoliagePerDay:    t.FoliagePerDay().Hours(),
+		Insolation:       t.Insolation,
+		InsolationPerDay: t.Insolation / days,
+	}
+}
+
+// hexColor returns c as a CSS hex color.
+func hexColor(c color.Color) string {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return fmt.Sprintf("#%02x%02x%02x", n.R, n.G, n.B)
+}
+
+// hexPalette returns n colors evenly sampled from p as CSS hex colors.
+func hexPalette(p palette.Palette, n int) []string {
+	colors := p.Colors()
+	out := make([]string, n)
+	for i := range out {
+		out[i] = hexColor(colors[i*(len(colors)-1)/(n-1)])
+	}
+	return out
+}
@@ -0,0 +1,42 @@
+package chart
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aclements/shade"
+	"github.com/aclements/shade/solar"
+)
+
+func TestHTMLReport(t *testing.T) {
+	// Two days at one minute increments, which the report should
+	// subsample.
+	var series []solar.SunLight
+	start := time.Date(2022, 5, 30, 0, 0, 0, 0, time.UTC)
+	for m := 0; m < 2*24*60; m++ {
+		sun := solar.SunLight{SunPos: solar.SunPos{T: start.Add(time.Duration(m) * time.Minute), Altitude: -10}}
+		if hod := m / 60 % 24; 8 <= hod && hod < 16 {
+			sun.Altitude, sun.Light = 45, 1
+		}
+		series = append(series, sun)
+	}
+
+	o := shade.NewIntensityOverTime(series, 0, time.Minute)
+	var buf strings.Builder
+	if err := WriteHTMLReport(&buf, o, "Test <report>", DefaultPlotOptions); err != nil {
+		t.Fatal(err)
+	}
+	html := buf.String()
+	for _, want := range []string{"<title>Test &lt;report&gt;</title>", `"step":60`, "<td>2022-05-30 – 2022-05-31</td>", "<td>Total</td>"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("report doesn't contain %q", want)
+		}
+	}
+	// The report must be self-contained.
+	for _, bad := range []string{"http:", "https:", "src="} {
+		if strings.Contains(html, bad) {
+			t.Errorf("report contains %q", bad)
+		}
+	}
+}
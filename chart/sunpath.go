@@ -0,0 +1,157 @@
+package chart
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"time"
+
+	"github.com/aclements/shade"
+	"github.com/aclements/shade/solar"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// SunPath returns a polar sun-path diagram for testPos over year in
+// loc. This shows the sun's track across the sky on the 21st of each
+// month, overlaid on the obstruction skyline around testPos. The center
+// of the diagram is the zenith and the edge is the horizon, with north
+// up. Hour lines are in loc's standard time.
+func SunPath(m *shade.ShadeModel, year int, loc *time.Location, testPos [3]float64, opts PlotOptions) *plot.Plot {
+	plt := newPlot(opts.theme())
+	plt.Title.Text = "Sun path"
+	plt.HideAxes()
+
+	c := &sunPathChart{m.Skyline(testPos, 1), solar.GetTracks(year, loc, m.Latitude(), m.Longitude())}
+	plt.Add(c)
+
+	plt.Legend.Add("Buildings", &plotter.Polygon{Color: sunPathBuilding})
+	plt.Legend.Add("Foliage", &plotter.Polygon{Color: sunPathFoliage})
+	plt.Legend.Add("Jun 21", &plotter.Line{LineStyle: draw.LineStyle{Color: sunPathSummer, Width: vg.Points(2)}})
+	plt.Legend.Add("Mar 20/Sep 22", &plotter.Line{LineStyle: draw.LineStyle{Color: sunPathEquinox, Width: vg.Points(2)}})
+	plt.Legend.Add("Dec 21", &plotter.Line{LineStyle: draw.LineStyle{Color: sunPathWinter, Width: vg.Points(2)}})
+	return plt
+}
+
+var (
+	sunPathBuilding = color.RGBA{0x80, 0x80, 0x80, 0xff}
+	sunPathFoliage  = color.RGBA{0x20, 0x80, 0x20, 0xff}
+	sunPathSummer   = color.RGBA{0xff, 0x60, 0x00, 0xff}
+	sunPathEquinox  = color.RGBA{0xff, 0xc0, 0x00, 0xff}
+	sunPathWinter   = color.RGBA{0x60, 0xa0, 0xff, 0xff}
+	sunPathGrid     = color.Gray{0x60}
+)
+
+// sunPathChart is a plot.Plotter that draws a polar sun-path diagram.
+// It draws directly in canvas coordinates so the diagram is circular
+// regardless of the aspect ratio of the plot.
+type sunPathChart struct {
+	skyline *shade.Skyline
+	*solar.Tracks
+}
+
+func (s *sunPathChart) Plot(c draw.Canvas, plt *plot.Plot) {
+	center := c.Center()
+	size := c.Size()
+	radius := 0.9 * vg.Length(math.Min(float64(size.X), float64(size.Y))) / 2
+	proj := func(alt, az float64) vg.Point {
+		const deg2rad = math.Pi / 180
+		r := radius * vg.Length((90-alt)/90)
+		return vg.Point{
+			X: center.X + r*vg.Length(math.Sin(az*deg2rad)),
+			Y: center.Y + r*vg.Length(math.Cos(az*deg2rad)),
+		}
+	}
+	track := func(track []solar.SunPos) [][]vg.Point {
+		// Split the track into segments above the horizon.
+		var out [][]vg.Point
+		var cur []vg.Point
+		for _, p := range track {
+			if p.Altitude < 0 {
+				if len(cur) > 1 {
+					out = append(out, cur)
+				}
+				cur = nil
+				continue
+			}
+			cur = append(cur, proj(p.Altitude, p.Azimuth))
+		}
+		if len(cur) > 1 {
+			out = append(out, cur)
+		}
+		return out
+	}
+
+	// Draw the skyline as a band between the horizon and the top of the
+	// obstructions. Foliage goes first so buildings are drawn over it.
+	sky := s.skyline
+	band := func(alts func(i int) float64) []vg.Point {
+		var pts []vg.Point
+		for _, az := range sky.Azimuth {
+			pts = append(pts, proj(0, az))
+		}
+		pts = append(pts, proj(0, 360))
+		pts = append(pts, proj(alts(0), 360))
+		for i := len(sky.Azimuth) - 1; i >= 0; i-- {
+			pts = append(pts, proj(alts(i), sky.Azimuth[i]))
+		}
+		return pts
+	}
+	c.FillPolygon(sunPathFoliage, band(func(i int) float64 { return math.Max(sky.Building[i], sky.Foliage[i]) }))
+	c.FillPolygon(sunPathBuilding, band(func(i int) float64 { return sky.Building[i] }))
+
+	// Draw the altitude and azimuth grid.
+	grid := draw.LineStyle{Color: sunPathGrid, Width: vg.Points(0.5)}
+	label := plt.Legend.TextStyle
+	label.Color = sunPathGrid
+	label.XAlign, label.YAlign = draw.XCenter, draw.YCenter
+	for alt := 0.0; alt < 90; alt += 15 {
+		var ring []vg.Point
+		for az := 0.0; az <= 360; az += 2 {
+			ring = append(ring, proj(alt, az))
+		}
+		c.StrokeLines(grid, ring)
+		if alt > 0 {
+			c.FillText(label, proj(alt, 180), fmt.Sprintf("%g°", alt))
+		}
+	}
+	for az := 0.0; az < 360; az += 30 {
+		c.StrokeLines(grid, []vg.Point{proj(90, az), proj(0, az)})
+	}
+	label.Color = plt.Legend.TextStyle.Color
+	for i, dir := range []string{"N", "E", "S", "W"} {
+		c.FillText(label, proj(-8, float64(i)*90), dir)
+	}
+
+	// Draw the sun's tracks.
+	thin := draw.LineStyle{Color: color.Gray{0xa0}, Width: vg.Points(0.5)}
+	for _, m := range s.Months {
+		c.StrokeLines(thin, track(m)...)
+	}
+	for _, h := range s.Hours {
+		c.StrokeLines(thin, track(h)...)
+	}
+	for _, t := range []struct {
+		track []solar.SunPos
+		color color.Color
+	}{
+		{s.Summer, sunPathSummer},
+		{s.Equinox, sunPathEquinox},
+		{s.Winter, sunPathWinter},
+	} {
+		c.StrokeLines(draw.LineStyle{Color: t.color, Width: vg.Points(2)}, track(t.track)...)
+	}
+
+	// Label the hours where they cross the summer track, which is the
+	// longest.
+	label.Color = plt.Legend.TextStyle.Color
+	for hour, h := range s.Hours {
+		if p := h[time.June-1]; p.Altitude >= 0 {
+			pt := proj(p.Altitude, p.Azimuth)
+			pt.Y += label.Font.Size
+			c.FillText(label, pt, fmt.Sprint(hour))
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package chart
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/palette"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// A Theme is the color scheme of plots.
+type Theme struct {
+	Name                   string
+	Background, Foreground color.Color
+}
+
+var (
+	// DarkTheme is white on black, for screens.
+	DarkTheme = &Theme{"dark", color.Black, color.White}
+	// LightTheme is black on white, for printing.
+	LightTheme = &Theme{"light", color.White, color.Black}
+
+	Themes = []*Theme{DarkTheme, LightTheme}
+)
+
+// A SunPalette is the colors used for sun intensity in heat maps.
+type SunPalette struct {
+	Name string
+
+	// Direct colors the intensity of direct sun, from dark to light.
+	Direct palette.Palette
+
+	// Foliage colors the intensity of sun filtered through foliage. It
+	// must be distinguishable from Direct.
+	Foliage palette.Palette
+
+	// Overflow colors intensities above the range of the color scale.
+	// It must be distinguishable from both Direct and Foliage.
+	Overflow color.Color
+
+	// Night colors times when the sun is down. It must be
+	// distinguishable from Direct, Foliage, and Overflow, and
+	// especially from the ends of Direct, so night doesn't look like
+	// shade or full sun.
+	Night color.Color
+}
+
+var (
+	// HeatPalette is black through red to white, with foliage shade
+	// in green. This is hard to read for people with red-green color
+	// blindness.
+	HeatPalette = &SunPalette{"heat", palette.Heat(256, 1), foliagePalette{palette.Heat(256, 1)}, color.RGBA{0x00, 0xc0, 0xff, 0xff}, color.RGBA{0x18, 0x20, 0x58, 0xff}}
+
+	// ViridisPalette is a perceptually uniform, color-blind safe
+	// palette from purple through green to yellow, with foliage shade
+	// in gray.
+	ViridisPalette = &SunPalette{
+		"viridis",
+		interpPalette(256,
+			color.RGBA{0x44, 0x01, 0x54, 0xff}, color.RGBA{0x48, 0x25, 0x76, 0xff},
+			color.RGBA{0x41, 0x44, 0x87, 0xff}, color.RGBA{0x35, 0x60, 0x8d, 0xff},
+			color.RGBA{0x2a, 0x78, 0x8e, 0xff}, color.RGBA{0x21, 0x90, 0x8c, 0xff},
+			color.RGBA{0x22, 0xa8, 0x84, 0xff}, color.RGBA{0x43, 0xbf, 0x71, 0xff},
+			color.RGBA{0x7a, 0xd1, 0x51, 0xff}, color.RGBA{0xbb, 0xdf, 0x27, 0xff},
+			color.RGBA{0xfd, 0xe7, 0x25, 0xff}),
+		interpPalette(256, color.RGBA{0x20, 0x20, 0x20, 0xff}, color.RGBA{0xe0, 0xe0, 0xe0, 0xff}),
+		color.RGBA{0xe7, 0x29, 0x8a, 0xff},
+		color.RGBA{0x70, 0x28, 0x18, 0xff},
+	}
+
+	// CividisPalette is a perceptually uniform palette from blue to
+	// yellow designed to look nearly the same with and without color
+	// vision deficiencies, with foliage shade in reddish purple.
+	CividisPalette = &SunPalette{
+		"cividis",
+		interpPalette(256,
+			color.RGBA{0x00, 0x22, 0x4e, 0xff}, color.RGBA{0x12, 0x35, 0x70, 0xff},
+			color.RGBA{0x3b, 0x49, 0x6c, 0xff}, color.RGBA{0x57, 0x5d, 0x6d, 0xff},
+			color.RGBA{0x70, 0x71, 0x73, 0xff}, color.RGBA{0x8a, 0x86, 0x78, 0xff},
+			color.RGBA{0xa5, 0x9c, 0x74, 0xff}, color.RGBA{0xc3, 0xb3, 0x69, 0xff},
+			color.RGBA{0xe1, 0xcc, 0x55, 0xff}, color.RGBA{0xfe, 0xe8, 0x38, 0xff}),
+		interpPalette(256, color.RGBA{0x3d, 0x0a, 0x2e, 0xff}, color.RGBA{0xcc, 0x79, 0xa7, 0xff}, color.RGBA{0xf8, 0xe0, 0xee, 0xff}),
+		color.RGBA{0x00, 0x9e, 0x73, 0xff},
+		color.RGBA{0x68, 0x30, 0x10, 0xff},
+	}
+
+	SunPalettes = []*SunPalette{HeatPalette, ViridisPalette, CividisPalette}
+)
+
+// paletteColors is a palette.Palette of fixed colors.
+type paletteColors []color.Color
+
+func (p paletteColors) Colors() []color.Color {
+	return p
+}
+
+// interpPalette returns a palette of n colors linearly interpolated
+// between evenly spaced anchors.
+func interpPalette(n int, anchors ...color.RGBA) palette.Palette {
+	out := make(paletteColors, n)
+	for i := range out {
+		f := float64(i) / float64(n-1) * float64(len(anchors)-1)
+		j := int(f)
+		if j >= len(anchors)-1 {
+			out[i] = anchors[len(anchors)-1]
+			continue
+		}
+		out[i] = lerpRGBA(anchors[j], anchors[j+1], f-float64(j))
+	}
+	return out
+}
+
+func lerpRGBA(a, b color.RGBA, f float64) color.RGBA {
+	l := func(x, y uint8) uint8 { return uint8(float64(x) + f*(float64(y)-float64(x))) }
+	return color.RGBA{l(a.R, b.R), l(a.G, b.G), l(a.B, b.B), l(a.A, b.A)}
+}
+
+// reversePalette returns p in reverse order.
+func reversePalette(p palette.Palette) palette.Palette {
+	c := p.Colors()
+	out := make(paletteColors, len(c))
+	for i := range c {
+		out[len(c)-1-i] = c[i]
+	}
+	return out
+}
+
+// paletteMap is a palette.ColorMap over the range [min, max] backed by
+// a palette.Palette.
+type paletteMap struct {
+	colors   []color.Color
+	min, max float64
+	alpha    float64
+}
+
+func newPaletteMap(p palette.Palette, min, max float64) *paletteMap {
+	return &paletteMap{p.Colors(), min, max, 1}
+}
+
+func (m *paletteMap) At(v float64) (color.Color, error) {
+	switch {
+	case math.IsNaN(v):
+		return nil, palette.ErrNaN
+	case v < m.min:
+		return nil, palette.ErrUnderflow
+	case v > m.max:
+		return nil, palette.ErrOverflow
+	}
+	i := 0
+	if m.max > m.min {
+		i = int((v - m.min) / (m.max - m.min) * float64(len(m.colors)-1))
+	}
+	return m.colors[i], nil
+}
+
+func (m *paletteMap) Max() float64           { return m.max }
+func (m *paletteMap) Min() float64           { return m.min }
+func (m *paletteMap) SetMax(v float64)       { m.max = v }
+func (m *paletteMap) SetMin(v float64)       { m.min = v }
+func (m *paletteMap) Alpha() float64         { return m.alpha }
+func (m *paletteMap) SetAlpha(alpha float64) { m.alpha = alpha }
+
+func (m *paletteMap) Palette(colors int) palette.Palette {
+	out := make(paletteColors, colors)
+	for i := range out {
+		out[i] = m.colors[i*(len(m.colors)-1)/int(math.Max(1, float64(colors-1)))]
+	}
+	return out
+}
+
+// A ColorBarPlot is a plot with one or more continuous color bars to its
+// right that serve as the legend of its heat maps.
+type ColorBarPlot struct {
+	*plot.Plot
+	Bars []*plot.Plot
+
+	theme *Theme
+}
+
+// newColorBarPlot returns a new ColorBarPlot in theme.
+func newColorBarPlot(theme *Theme) *ColorBarPlot {
+	return &ColorBarPlot{Plot: newPlot(theme), theme: theme}
+}
+
+// colorBarWidth is the width of each color bar, including its labels.
+const colorBarWidth = 2.5 * vg.Centimeter
+
+// AddColorBar adds a color bar showing palette pal over [min, max]. If
+// overflow is non-nil, the bar also shows that values above max are
+// drawn in color overflow.
+func (p *ColorBarPlot) AddColorBar(label string, pal palette.Palette, min, max float64, overflow color.Color) {
+	bar := newPlot(p.theme)
+	bar.Title.Text = label
+	bar.Title.TextStyle.Font.Size = bar.Legend.TextStyle.Font.Size
+	bar.HideX()
+	bar.Y.Tick.Marker = plot.DefaultTicks{}
+	bar.Add(&plotter.ColorBar{ColorMap: newPaletteMap(pal, min, max), Vertical: true})
+	if overflow != nil {
+		sw := &overflowSwatch{max, max + 0.08*(max-min), overflow}
+		bar.Add(sw)
+		bar.Y.Tick.Marker = sw
+	}
+	p.Bars = append(p.Bars, bar)
+}
+
+// overflowSwatch is a plot.Plotter that draws a block of color above a
+// vertical color bar to show the color of values above the bar's
+// range. It's also the plot.Ticker for the bar.
+type overflowSwatch struct {
+	min, max float64
+	color    color.Color
+}
+
+func (s *overflowSwatch) Plot(c draw.Canvas, plt *plot.Plot) {
+	trX, trY := plt.Transforms(&c)
+	// Leave a gap so the swatch isn't mistaken for part of the bar.
+	gap := (s.max - s.min) / 4
+	rect := vg.Rectangle{
+		Min: vg.Point{X: trX(0), Y: trY(s.min + gap)},
+		Max: vg.Point{X: trX(1), Y: trY(s.max)},
+	}
+	c.SetColor(s.color)
+	c.Fill(rect.Path())
+}
+
+func (s *overflowSwatch) DataRange() (xmin, xmax, ymin, ymax float64) {
+	return 0, 1, s.min, s.max
+}
+
+func (s *overflowSwatch) Ticks(min, max float64) []plot.Tick {
+	var ticks []plot.Tick
+	for _, t := range (plot.DefaultTicks{}).Ticks(min, s.min) {
+		if t.Value < s.min {
+			ticks = append(ticks, t)
+		}
+	}
+	// Label the top of the bar so it's clear where overflow starts.
+	ticks = append(ticks, plot.Tick{Value: s.min, Label: fmt.Sprintf("%.4g", s.min)})
+	gap := (s.max - s.min) / 4
+	ticks = append(ticks, plot.Tick{Value: (s.min + gap + s.max) / 2, Label: "over"})
+	return ticks
+}
+
+// Draw draws the plot and its color bars to c.
+func (p *ColorBarPlot) Draw(c draw.Canvas) {
+	if p.BackgroundColor != nil {
+		c.SetColor(p.BackgroundColor)
+		c.Fill(c.Rectangle.Path())
+	}
+	main := c
+	main.Max.X -= vg.Length(len(p.Bars)) * colorBarWidth
+	p.Plot.Draw(main)
+	for i, bar := range p.Bars {
+		bc := c
+		bc.Min.X = main.Max.X + vg.Length(i)*colorBarWidth
+		bc.Max.X = bc.Min.X + colorBarWidth
+		// Leave room for the main plot's X axis, so the bars line up
+		// roughly with its data area.
+		bc.Min.Y += 2 * p.X.Tick.Label.Font.Size
+		bar.Draw(bc)
+	}
+}
+
+// ParseTheme returns the Theme with the given name.
+func ParseTheme(name string) (*Theme, error) {
+	for _, t := range Themes {
+		if t.Name == name {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown theme %q", name)
+}
+
+// ParseSunPalette returns the SunPalette with the given name.
+func ParseSunPalette(name string) (*SunPalette, error) {
+	for _, p := range SunPalettes {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown palette %q", name)
+}
+
+// newPlot returns a new plot in theme, or DarkTheme if theme is nil.
+func newPlot(theme *Theme) *plot.Plot {
+	if theme == nil {
+		theme = DarkTheme
+	}
+	plt := plot.New()
+	plt.Legend.Top = true
+	plt.Legend.Padding = 0.5 * plt.Legend.TextStyle.Font.Size
+
+	plt.BackgroundColor = theme.Background
+	for _, elt := range []*color.Color{
+		&plt.Title.TextStyle.Color,
+		&plt.X.Color,
+		&plt.X.Tick.Color,
+		&plt.X.Tick.Label.Color,
+		&plt.X.Label.TextStyle.Color,
+		&plt.Y.Color,
+		&plt.Y.Tick.Color,
+		&plt.Y.Tick.Label.Color,
+		&plt.Y.Label.TextStyle.Color,
+		&plt.Legend.TextStyle.Color,
+	} {
+		*elt = theme.Foreground
+	}
+	return plt
+}
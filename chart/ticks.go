@@ -1,4 +1,4 @@
-package main
+package chart
 
 import (
 	"fmt"
@@ -137,32 +137,32 @@ func (dayOfYearTicks) Ticks(min, max float64) []plot.Tick {
 	return ticks
 }
 
-type solsticeTicks struct{}
-
-func (solsticeTicks) Ticks(min, max float64) []plot.Tick {
-	valToTime := plot.UTCUnixTime
-	minT, maxT := valToTime(min), valToTime(max)
-	year := minT.Year()
-	var ticks []plot.Tick
-	ticks = append(ticks, plot.Tick{
-		Value: min,
-		Label: minT.Format("1/02/2006"),
-	})
-	add := func(month time.Month, day int) {
-		t := time.Date(year, month, day, 12, 0, 0, 0, time.UTC)
-		if t.Before(minT) || t.After(maxT) {
-			return
+// seasonDates returns the approximate dates of the equinoxes and
+// solstices between minT and maxT. Like splitTime, each date is at noon
+// UTC.
+func seasonDates(minT, maxT time.Time) []time.Time {
+	var dates []time.Time
+	for year := minT.Year(); year <= maxT.Year(); year++ {
+		for _, d := range []struct {
+			month time.Month
+			day   int
+		}{{3, 20}, {6, 21}, {9, 22}, {12, 22}} {
+			t := time.Date(year, d.month, d.day, 12, 0, 0, 0, time.UTC)
+			if !t.Before(minT) && !t.After(maxT) {
+				dates = append(dates, t)
+			}
 		}
-		ticks = append(ticks, plot.Tick{
-			Value: float64(t.Unix()),
-			Label: t.Format("1/02"),
-		})
-	}
-	for ; year <= maxT.Year(); year++ {
-		add(3, 20)
-		add(6, 21)
-		add(9, 22)
-		add(12, 22)
 	}
-	return ticks
+	return dates
+}
+
+// splitTime splits t into day and time of day. For the day, we put it
+// at noon to "center" it on that date. We put the result in UTC since
+// that's the time zone gonum will render it in and it avoids further
+// complications with DST. Time of day is returned as a duration since
+// midnight.
+func splitTime(t time.Time) (day time.Time, tod time.Duration) {
+	day = time.Date(t.Year(), t.Month(), t.Day(), 12, 0, 0, 0, time.UTC)
+	tod = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())*time.Nanosecond
+	return
 }
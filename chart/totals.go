@@ -0,0 +1,47 @@
+package chart
+
+import (
+	"image/color"
+
+	"github.com/aclements/shade"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+)
+
+// MonthlySunHours returns a bar chart of the average hours of direct
+// and foliage-filtered sun per day in each month.
+func MonthlySunHours(o *shade.IntensityOverTime, opts PlotOptions) (*plot.Plot, error) {
+	totals := o.MonthlyTotals()
+	var names []string
+	var direct, foliage plotter.Values
+	for _, t := range totals {
+		names = append(names, t.Start.Format("Jan"))
+		direct = append(direct, t.DirectPerDay().Hours())
+		foliage = append(foliage, t.FoliagePerDay().Hours())
+	}
+
+	plt := newPlot(opts.theme())
+	plt.Title.Text = "Average sun per day"
+	plt.Y.Label.Text = "Hours"
+	plt.NominalX(names...)
+
+	width := vg.Points(20)
+	dBars, err := plotter.NewBarChart(direct, width)
+	if err != nil {
+		return nil, err
+	}
+	dBars.Color = color.RGBA{0xff, 0xc0, 0x00, 0xff}
+	dBars.LineStyle.Width = 0
+	fBars, err := plotter.NewBarChart(foliage, width)
+	if err != nil {
+		return nil, err
+	}
+	fBars.Color = color.RGBA{0x40, 0xc0, 0x40, 0xff}
+	fBars.LineStyle.Width = 0
+	fBars.StackOn(dBars)
+	plt.Add(dBars, fBars)
+	plt.Legend.Add("Direct sun", dBars)
+	plt.Legend.Add("Foliage-filtered sun", fBars)
+	return plt, nil
+}
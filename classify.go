@@ -0,0 +1,179 @@
+package shade
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A SunCategory is a horticultural classification of sun exposure,
+// based on the hours of direct sun per day.
+type SunCategory int
+
+const (
+	FullShade SunCategory = iota // Less than 2 hours of direct sun
+	PartShade                    // 2 to 4 hours of direct sun
+	PartSun                      // 4 to 6 hours of direct sun
+	FullSun                      // 6 or more hours of direct sun
+)
+
+func (c SunCategory) String() string {
+	switch c {
+	case FullShade:
+		return "full shade"
+	case PartShade:
+		return "part shade"
+	case PartSun:
+		return "part sun"
+	case FullSun:
+		return "full sun"
+	}
+	return fmt.Sprintf("SunCategory(%d)", int(c))
+}
+
+// categorizeSun returns the SunCategory of a day with the given
+// duration of direct sun.
+func categorizeSun(direct time.Duration) SunCategory {
+	switch {
+	case direct >= 6*time.Hour:
+		return FullSun
+	case direct >= 4*time.Hour:
+		return PartSun
+	case direct >= 2*time.Hour:
+		return PartShade
+	}
+	return FullShade
+}
+
+// A GrowingSeason is a range of months, inclusive.
+type GrowingSeason struct {
+	Start, End time.Month
+}
+
+// DefaultGrowingSeason is a typical growing season for northern
+// mid-latitudes.
+var DefaultGrowingSeason = GrowingSeason{time.April, time.October}
+
+func (s GrowingSeason) contains(m time.Month) bool {
+	if s.Start <= s.End {
+		return s.Start <= m && m <= s.End
+	}
+	// The season wraps around the new year.
+	return m >= s.Start || m <= s.End
+}
+
+// A SunClass is the SunCategory of a period of time.
+type SunClass struct {
+	// Start and End are the bounds of this period. End is exclusive.
+	Start, End time.Time
+
+	Category SunCategory
+
+	// Confidence is the fraction of days in this period that fall in
+	// Category.
+	Confidence float64
+
+	// MedianDirect is the median duration of direct sun per day.
+	// Category is based on this.
+	MedianDirect time.Duration
+}
+
+// A SunClassification is the horticultural sun exposure of a test
+// point over a growing season.
+type SunClassification struct {
+	// Season is the classification over the whole growing season.
+	Season SunClass
+
+	// Months is the classification of each month of the growing
+	// season.
+	Months []SunClass
+}
+
+// ClassifySun classifies the sun exposure of o over each month of the
+// growing season, based on the daily duration of direct sun.
+func (o *IntensityOverTime) ClassifySun(season GrowingSeason) *SunClassification {
+	var c SunClassification
+	var all, month []SunTotals
+	for _, day := range o.DailyTotals() {
+		if !season.contains(day.Start.Month()) {
+			continue
+		}
+		if len(month) > 0 && month[0].Start.Month() != day.Start.Month() {
+			c.Months = append(c.Months, classifyDays(month))
+			month = nil
+		}
+		month = append(month, day)
+		all = append(all, day)
+	}
+	if len(month) > 0 {
+		c.Months = append(c.Months, classifyDays(month))
+	}
+	if len(all) > 0 {
+		c.Season = classifyDays(all)
+	}
+	return &c
+}
+
+func classifyDays(days []SunTotals) SunClass {
+	direct := make([]time.Duration, len(days))
+	for i, d := range days {
+		direct[i] = d.Direct
+	}
+	sort.Slice(direct, func(i, j int) bool { return direct[i] < direct[j] })
+	median := direct[len(direct)/2]
+	if len(direct)%2 == 0 {
+		median = (direct[len(direct)/2-1] + median) / 2
+	}
+	cat := categorizeSun(median)
+	n := 0
+	for _, d := range direct {
+		if categorizeSun(d) == cat {
+			n++
+		}
+	}
+	return SunClass{
+		Start:        days[0].Start,
+		End:          days[len(days)-1].End,
+		Category:     cat,
+		Confidence:   float64(n) / float64(len(days)),
+		MedianDirect: median,
+	}
+}
+
+// plantsBySun lists some common garden plants suited to each
+// SunCategory.
+var plantsBySun = map[SunCategory][]string{
+	FullSun:   {"tomato", "pepper", "squash", "basil", "lavender", "coneflower", "black-eyed Susan", "zinnia", "sunflower"},
+	PartSun:   {"bush bean", "pea", "beet", "carrot", "parsley", "daylily", "salvia", "coreopsis"},
+	PartShade: {"lettuce", "spinach", "kale", "chard", "mint", "astilbe", "bleeding heart", "columbine"},
+	FullShade: {"hosta", "fern", "foamflower", "wild ginger", "lily of the valley", "sweet woodruff"},
+}
+
+// WriteReport writes a human-readable report of c to w. If suggest is
+// true, it includes suggestions of plants suited to the season's
+// classification.
+func (c *SunClassification) WriteReport(w io.Writer, suggest bool) error {
+	var buf strings.Builder
+	if len(c.Months) == 0 {
+		fmt.Fprintf(&buf, "No days in growing season\n")
+	} else {
+		fmt.Fprintf(&buf, "Growing season: %s (median %s direct sun/day, %.0f%% of days)\n",
+			c.Season.Category, fmtHours(c.Season.MedianDirect), 100*c.Season.Confidence)
+		for _, m := range c.Months {
+			fmt.Fprintf(&buf, "  %s: %-10s  median %s/day, %3.0f%% of days\n",
+				m.Start.Format("Jan"), m.Category, fmtHours(m.MedianDirect), 100*m.Confidence)
+		}
+		if suggest {
+			fmt.Fprintf(&buf, "Suggested plants for %s: %s\n", c.Season.Category, strings.Join(plantsBySun[c.Season.Category], ", "))
+		}
+	}
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
+
+// fmtHours formats d as fractional hours, like "6.2h".
+func fmtHours(d time.Duration) string {
+	return fmt.Sprintf("%.1fh", d.Hours())
+}
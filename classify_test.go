@@ -0,0 +1,45 @@
+package shade
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aclements/shade/solar"
+)
+
+func TestClassifySun(t *testing.T) {
+	// In June, most days get 7 hours of direct sun, but a few get only
+	// 3. In July, every day gets 5 hours.
+	o := &IntensityOverTime{increment: time.Hour}
+	for day := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC); day.Month() <= time.July; day = day.AddDate(0, 0, 1) {
+		sunHours := 5
+		if day.Month() == time.June {
+			sunHours = 7
+			if day.Day() <= 5 {
+				sunHours = 3
+			}
+		}
+		for h := 0; h < 24; h++ {
+			sun := solar.SunLight{SunPos: solar.SunPos{T: day.Add(time.Duration(h) * time.Hour), Altitude: 45}}
+			if h < sunHours {
+				sun.Light = 1
+			}
+			o.sunPos = append(o.sunPos, sun)
+		}
+	}
+
+	c := o.ClassifySun(GrowingSeason{time.June, time.July})
+	if len(c.Months) != 2 {
+		t.Fatalf("got %d months, want 2", len(c.Months))
+	}
+	if m := c.Months[0]; m.Category != FullSun || m.Confidence != 25.0/30 {
+		t.Errorf("June: got %s with confidence %v, want %s with confidence %v", m.Category, m.Confidence, FullSun, 25.0/30)
+	}
+	if m := c.Months[1]; m.Category != PartSun || m.Confidence != 1 {
+		t.Errorf("July: got %s with confidence %v, want %s with confidence 1", m.Category, m.Confidence, PartSun)
+	}
+	// 25 days at 7h, 36 days at 5h.
+	if c.Season.Category != PartSun || c.Season.MedianDirect != 5*time.Hour {
+		t.Errorf("season: got %s with median %s, want %s with median 5h", c.Season.Category, c.Season.MedianDirect, PartSun)
+	}
+}
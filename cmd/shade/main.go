@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/aclements/shade"
+	"github.com/aclements/shade/chart"
+	"github.com/aclements/shade/server"
+	"gonum.org/v1/plot/vg"
+)
+
+// Notes about SketchUp STL exports:
+//
+// The SketchUp mesh is in inches.
+//
+// The red/green/blue SketchUp coordinate system maps to STL like this:
+//
+//   blue/Z
+//     |  green/Y/North
+//     | /
+//     |/____ red/X
+
+var (
+	plotFormat  = flag.String("format", "png", "write plots in `format`: png, jpg, tiff, svg, pdf, or eps")
+	httpAddr    = flag.String("http", "", "serve the analysis API and viewer on `addr` instead of analyzing the built-in model")
+	plotOptions = chart.DefaultPlotOptions
+	heatMap     chart.HeatMapOptions
+)
+
+func init() {
+	lengthFlag := func(l *vg.Length) func(string) error {
+		return func(s string) (err error) {
+			*l, err = vg.ParseLength(s)
+			return
+		}
+	}
+	flag.Func("width", "plot `width`, such as 20cm or 8in (default 20cm)", lengthFlag(&plotOptions.Width))
+	flag.Func("height", "plot `height` (default 15cm)", lengthFlag(&plotOptions.Height))
+	flag.IntVar(&plotOptions.DPI, "dpi", plotOptions.DPI, "resolution of raster plot formats")
+	flag.Float64Var(&heatMap.Max, "heatmax", 0, "top of the heat map color scale in W/m² (default: highest intensity)")
+	flag.Func("overlays", "comma-separated heat map `overlays`: sun (sunrise/sunset), noon, seasons, and dst", func(s string) error {
+		for _, o := range strings.Split(s, ",") {
+			switch o {
+			case "sun":
+				heatMap.SunriseSunset = true
+			case "noon":
+				heatMap.SolarNoon = true
+			case "seasons":
+				heatMap.Seasons = true
+			case "dst":
+				heatMap.DST = true
+			default:
+				return fmt.Errorf("unknown overlay %q", o)
+			}
+		}
+		return nil
+	})
+	flag.Func("event", "mark `label=yyyy-mm-dd` on heat maps; may be repeated", func(s string) error {
+		label, date, ok := strings.Cut(s, "=")
+		if !ok {
+			return fmt.Errorf("want label=yyyy-mm-dd")
+		}
+		t, err := time.ParseInLocation("2006-01-02", date, time.Local)
+		if err != nil {
+			return err
+		}
+		heatMap.Events = append(heatMap.Events, chart.HeatMapEvent{Label: label, Date: t})
+		return nil
+	})
+	flag.Func("theme", "plot `theme`: dark or light (default dark)", func(s string) (err error) {
+		plotOptions.Theme, err = chart.ParseTheme(s)
+		return
+	})
+	flag.Func("palette", "heat map `palette`: heat, viridis, or cividis (default heat)", func(s string) (err error) {
+		plotOptions.Palette, err = chart.ParseSunPalette(s)
+		return
+	})
+}
+
+func main() {
+	flag.Parse()
+	log.SetFlags(0)
+	log.SetPrefix("shade: ")
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run() error {
+	// Stop analyses cleanly on ^C so they can checkpoint their
+	// progress.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if isTerminal(os.Stderr) {
+		ctx = shade.WithProgress(ctx, progressBar(os.Stderr))
+	}
+
+	// In this model, Z=0 is the 90' reference on the architectural
+	// drawings. That's close to 200' actual elevation.
+	const lat = 42.4195011
+	const lon = -71.2064993
+	const elev = 200
+	// Tree in the middle of the lower patio
+	//var testPos = [3]float64{0, 0, (5 + 5) * 12}
+	// Garage end of green roof
+	//var testPos = [3]float64{-15 * 12, -10 * 12, (10 + 5) * 12}
+	// House end of green roof
+	var testPos = [3]float64{-10 * 12, 8 * 12, (10 + 5) * 12}
+
+	m := shade.NewShadeModel(lat, lon, elev)
+	if err := m.AddBuildings("house.stl"); err != nil {
+		return err
+	}
+
+	//var cameraOffset = [3]float64{40 * 12, -30 * 12, 10 * 12}
+	//render.POV(m, testPos, cameraOffset, time.Date(2022, 6, 1, 12, 0, 0, 0, time.Local), "render.png")
+	//return nil
+
+	// Animate shadows over the summer solstice.
+	//anim := &render.Animation{TestPos: testPos, CameraOffset: cameraOffset, Width: 800, Height: 600}
+	//anim.Times = render.DaylightTimes(m, time.Date(2022, 6, 21, 0, 0, 0, 0, time.Local), 15*time.Minute)
+	//frames, _ := render.Frames(m, anim)
+	//f, _ := os.Create("shadows.gif")
+	//render.WriteGIF(f, frames, 200*time.Millisecond)
+	//f.Close()
+	//return nil
+
+	if err := m.AddFoliage("house-trees.stl"); err != nil {
+		return err
+	}
+
+	if *httpAddr != "" {
+		srv := server.New(".")
+		id := srv.AddSite(m, time.Local)
+		log.Printf("serving on %s; view the model at http://%s/sites/%s/view", *httpAddr, *httpAddr, id)
+		return http.ListenAndServe(*httpAddr, srv)
+	}
+
+	// What if we take down the trees?
+	//results, _ := m.CompareScenarios(ctx, 2022, time.Local, testPos, []*shade.Scenario{nil, {Name: "no trees", Disable: []string{"house-trees"}}})
+	//shade.WriteScenarioSummary(os.Stdout, results)
+	//plt, _ := chart.SunChangeMap(results[0].Intensity, results[1].Intensity, plotOptions)
+	//writePlot(plt, "change")
+	//f, _ := os.Create("impact.pdf")
+	//chart.WriteShadowImpact(f, m, nil, &shade.Scenario{Name: "no trees", Disable: []string{"house-trees"}}, 2022, time.Local, 0, 24, plotOptions)
+	//f.Close()
+	//return nil
+
+	intensity, err := m.IntensityOverYear(ctx, 2022, time.Local, testPos)
+	if errors.Is(err, context.Canceled) {
+		return fmt.Errorf("interrupted; run again to resume: %w", err)
+	} else if err != nil {
+		return err
+	}
+
+	sun, err := chart.HeatMap(intensity, &heatMap, plotOptions)
+	if err != nil {
+		return err
+	}
+	if err := writePlot(sun, "sun"); err != nil {
+		return err
+	}
+	duration, err := chart.ShadeDuration(intensity, &heatMap, plotOptions)
+	if err != nil {
+		return err
+	}
+	if err := writePlot(duration, "duration"); err != nil {
+		return err
+	}
+	//plt, _ := chart.MonthlySunHours(intensity, plotOptions)
+	//writePlot(plt, "hours")
+	//plt, _ = chart.DLIPlot(intensity, plotOptions)
+	//writePlot(plt, "dli")
+	//writePlot(chart.SunPath(m, 2022, time.Local, testPos, plotOptions), "sunpath")
+	//writePlot(chart.ShadowPlan(m, time.Date(2022, 7, 1, 15, 0, 0, 0, time.Local), 0, 12, plotOptions), "plan")
+	//solstice := time.Date(2022, 12, 21, 0, 0, 0, 0, time.Local)
+	//plt = chart.ShadeHoursPlan(m, solstice, solstice.AddDate(0, 0, 1), 10*time.Minute, 0, 24, plotOptions)
+	//writePlot(plt, "shadehours")
+	//render.SavePNG(render.Fisheye(m, 2022, time.Local, testPos, 800), "fisheye.png")
+	//f, _ := os.Create("report.html")
+	//chart.WriteHTMLReport(f, intensity, "Sun exposure", plotOptions)
+	//f.Close()
+	if err := shade.WriteTotalsTable(os.Stdout, intensity.MonthlyTotals()); err != nil {
+		return err
+	}
+	return intensity.ClassifySun(shade.DefaultGrowingSeason).WriteReport(os.Stdout, true)
+}
+
+// writePlot writes plt to name with the extension of the -format flag.
+func writePlot(plt chart.Drawer, name string) error {
+	return chart.WritePlot(plt, name+"."+*plotFormat, plotOptions)
+}
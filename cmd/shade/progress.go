@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aclements/shade"
+)
+
+// progressBar returns a function that draws a progress bar on w for
+// each shade.Progress report.
+func progressBar(w io.Writer) func(shade.Progress) {
+	const width = 40
+	return func(p shade.Progress) {
+		if p.Total == 0 {
+			return
+		}
+		frac := float64(p.Traced) / float64(p.Total)
+		n := int(frac * width)
+		bar := strings.Repeat("=", n) + strings.Repeat(" ", width-n)
+		// \033[K clears the rest of the line in case it got shorter.
+		fmt.Fprintf(w, "\r[%s] %3.0f%% %d/%d rays", bar, 100*frac, p.Traced, p.Total)
+		if p.ETA > 0 {
+			fmt.Fprintf(w, ", ETA %s", p.ETA.Round(time.Second))
+		}
+		fmt.Fprintf(w, "\033[K")
+		if p.Traced == p.Total {
+			fmt.Fprintln(w)
+		}
+	}
+}
+
+// isTerminal reports whether f is a terminal.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
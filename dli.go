@@ -0,0 +1,54 @@
+package shade
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// A DailyLight is the daily light integral (DLI) of a single day.
+type DailyLight struct {
+	Day time.Time
+
+	// DLI is the total photosynthetically active radiation received
+	// over the day, in mol/m²/day.
+	DLI float64
+}
+
+// DailyLightIntegral returns the daily light integral of each day of o.
+func (o *IntensityOverTime) DailyLightIntegral() []DailyLight {
+	var out []DailyLight
+	for _, day := range o.byDay() {
+		var dli float64
+		for _, sun := range day {
+			dli += sun.PPFD(o.elevationFeet) * o.increment.Seconds() / 1e6
+		}
+		out = append(out, DailyLight{day[0].T, dli})
+	}
+	return out
+}
+
+// MeanDLI returns the mean daily light integral over the growing season
+// of year in loc at each of points, in mol/m²/day. This is useful for
+// comparing many candidate spots, such as a grid over a garden bed.
+func (m *ShadeModel) MeanDLI(ctx context.Context, year int, loc *time.Location, points [][3]float64, season GrowingSeason) ([]float64, error) {
+	out := make([]float64, len(points))
+	for i, p := range points {
+		o, err := m.IntensityOverYear(ctx, year, loc, p)
+		if err != nil {
+			return nil, fmt.Errorf("point %v: %w", p, err)
+		}
+		var sum float64
+		var n int
+		for _, d := range o.DailyLightIntegral() {
+			if season.contains(d.Day.Month()) {
+				sum += d.DLI
+				n++
+			}
+		}
+		if n > 0 {
+			out[i] = sum / float64(n)
+		}
+	}
+	return out, nil
+}
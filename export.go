@@ -0,0 +1,195 @@
+package shade
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/aclements/shade/solar"
+)
+
+// A SeriesFormat is a file format for exporting the per-time-step sun
+// light series of an IntensityOverTime.
+type SeriesFormat int
+
+const (
+	// SeriesCSV is a CSV file with a header row and one row per time
+	// step.
+	SeriesCSV SeriesFormat = iota
+
+	// SeriesJSONL is JSON Lines, with one JSON object per time step.
+	SeriesJSONL
+
+	// SeriesColumns is a single JSON object that maps each column
+	// name to an array of values. This is compact and easy to load
+	// into data frames.
+	SeriesColumns
+)
+
+// SeriesFormatFromPath returns the SeriesFormat implied by path's
+// extension.
+func SeriesFormatFromPath(path string) (SeriesFormat, error) {
+	switch ext := filepath.Ext(path); ext {
+	case ".csv":
+		return SeriesCSV, nil
+	case ".jsonl", ".ndjson":
+		return SeriesJSONL, nil
+	case ".json":
+		return SeriesColumns, nil
+	default:
+		return 0, fmt.Errorf("unknown series format %q", ext)
+	}
+}
+
+// seriesColumns are the column names used by all series formats.
+var seriesColumns = []string{"time", "altitude", "azimuth", "light", "foliage", "global_intensity"}
+
+// seriesRecord is a single time step in the JSONL format.
+type seriesRecord struct {
+	Time            time.Time `json:"time"`
+	Altitude        float64   `json:"altitude"`
+	Azimuth         float64   `json:"azimuth"`
+	Light           float64   `json:"light"`
+	Foliage         bool      `json:"foliage"`
+	GlobalIntensity float64   `json:"global_intensity"`
+}
+
+// seriesColumnData is the SeriesColumns format.
+type seriesColumnData struct {
+	Time            []time.Time `json:"time"`
+	Altitude        []float64   `json:"altitude"`
+	Azimuth         []float64   `json:"azimuth"`
+	Light           []float64   `json:"light"`
+	Foliage         []bool      `json:"foliage"`
+	GlobalIntensity []float64   `json:"global_intensity"`
+}
+
+// WriteSeries writes the time series of o to w in the given format.
+// Each time step includes the sun position, the light multiplier and
+// foliage flag from SunLight, and the global intensity in W/m².
+func (o *IntensityOverTime) WriteSeries(w io.Writer, format SeriesFormat) error {
+	switch format {
+	case SeriesCSV:
+		cw := csv.NewWriter(w)
+		cw.Write(seriesColumns)
+		ff := func(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+		for _, sun := range o.sunPos {
+			cw.Write([]string{
+				sun.T.Format(time.RFC3339Nano),
+				ff(sun.Altitude),
+				ff(sun.Azimuth),
+				ff(sun.Light),
+				strconv.FormatBool(sun.Foliage),
+				ff(o.Intensity(sun)),
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+
+	case SeriesJSONL:
+		bw := bufio.NewWriter(w)
+		enc := json.NewEncoder(bw)
+		for _, sun := range o.sunPos {
+			rec := seriesRecord{sun.T, sun.Altitude, sun.Azimuth, sun.Light, sun.Foliage, o.Intensity(sun)}
+			if err := enc.Encode(&rec); err != nil {
+				return err
+			}
+		}
+		return bw.Flush()
+
+	case SeriesColumns:
+		var cols seriesColumnData
+		for _, sun := range o.sunPos {
+			cols.Time = append(cols.Time, sun.T)
+			cols.Altitude = append(cols.Altitude, sun.Altitude)
+			cols.Azimuth = append(cols.Azimuth, sun.Azimuth)
+			cols.Light = append(cols.Light, sun.Light)
+			cols.Foliage = append(cols.Foliage, sun.Foliage)
+			cols.GlobalIntensity = append(cols.GlobalIntensity, o.Intensity(sun))
+		}
+		return json.NewEncoder(w).Encode(&cols)
+	}
+	return fmt.Errorf("unknown series format %d", format)
+}
+
+// ReadSeries reads a time series written by WriteSeries. The global
+// intensity column is ignored and recomputed from elevationFeet, which
+// should be the elevation of the original model. The time increment is
+// taken from the first two time steps.
+func ReadSeries(r io.Reader, format SeriesFormat, elevationFeet float64) (*IntensityOverTime, error) {
+	var sunPos []solar.SunLight
+	switch format {
+	case SeriesCSV:
+		cr := csv.NewReader(r)
+		cr.FieldsPerRecord = len(seriesColumns)
+		if _, err := cr.Read(); err != nil {
+			return nil, fmt.Errorf("reading CSV header: %w", err)
+		}
+		for {
+			rec, err := cr.Read()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			var sun solar.SunLight
+			if sun.T, err = time.Parse(time.RFC3339Nano, rec[0]); err != nil {
+				return nil, err
+			}
+			for i, p := range []*float64{&sun.Altitude, &sun.Azimuth, &sun.Light} {
+				if *p, err = strconv.ParseFloat(rec[1+i], 64); err != nil {
+					return nil, err
+				}
+			}
+			if sun.Foliage, err = strconv.ParseBool(rec[4]); err != nil {
+				return nil, err
+			}
+			sunPos = append(sunPos, sun)
+		}
+
+	case SeriesJSONL:
+		dec := json.NewDecoder(r)
+		for {
+			var rec seriesRecord
+			if err := dec.Decode(&rec); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			sunPos = append(sunPos, solar.SunLight{SunPos: solar.SunPos{T: rec.Time, Altitude: rec.Altitude, Azimuth: rec.Azimuth}, Light: rec.Light, Foliage: rec.Foliage})
+		}
+
+	case SeriesColumns:
+		var cols seriesColumnData
+		if err := json.NewDecoder(r).Decode(&cols); err != nil {
+			return nil, err
+		}
+		n := len(cols.Time)
+		if len(cols.Altitude) != n || len(cols.Azimuth) != n || len(cols.Light) != n || len(cols.Foliage) != n {
+			return nil, fmt.Errorf("columns have different lengths")
+		}
+		for i := range cols.Time {
+			sunPos = append(sunPos, solar.SunLight{SunPos: solar.SunPos{T: cols.Time[i], Altitude: cols.Altitude[i], Azimuth: cols.Azimuth[i]}, Light: cols.Light[i], Foliage: cols.Foliage[i]})
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown series format %d", format)
+	}
+
+	if len(sunPos) == 0 {
+		return nil, fmt.Errorf("empty series")
+	}
+	increment := time.Minute
+	if len(sunPos) > 1 {
+		increment = sunPos[1].T.Sub(sunPos[0].T)
+		if increment <= 0 {
+			return nil, fmt.Errorf("time steps are not increasing")
+		}
+	}
+	return &IntensityOverTime{sunPos, elevationFeet, increment}, nil
+}
@@ -0,0 +1,44 @@
+package shade
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/aclements/shade/solar"
+)
+
+func TestSeriesRoundTrip(t *testing.T) {
+	start := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	o := &IntensityOverTime{elevationFeet: 200, increment: time.Minute}
+	for i := 0; i < 3; i++ {
+		o.sunPos = append(o.sunPos, solar.SunLight{
+			SunPos:  solar.SunPos{T: start.Add(time.Duration(i) * time.Minute), Altitude: 60.125 + float64(i), Azimuth: 180.5},
+			Light:   0.05 * float64(i),
+			Foliage: i == 1,
+		})
+	}
+
+	for _, format := range []SeriesFormat{SeriesCSV, SeriesJSONL, SeriesColumns} {
+		var buf bytes.Buffer
+		if err := o.WriteSeries(&buf, format); err != nil {
+			t.Fatalf("format %d: writing: %s", format, err)
+		}
+		o2, err := ReadSeries(&buf, format, o.elevationFeet)
+		if err != nil {
+			t.Fatalf("format %d: reading: %s", format, err)
+		}
+		if o2.increment != o.increment {
+			t.Errorf("format %d: got increment %s, want %s", format, o2.increment, o.increment)
+		}
+		if len(o2.sunPos) != len(o.sunPos) {
+			t.Fatalf("format %d: got %d time steps, want %d", format, len(o2.sunPos), len(o.sunPos))
+		}
+		for i, want := range o.sunPos {
+			got := o2.sunPos[i]
+			if !got.T.Equal(want.T) || got.Altitude != want.Altitude || got.Azimuth != want.Azimuth || got.Light != want.Light || got.Foliage != want.Foliage {
+				t.Errorf("format %d: step %d: got %+v, want %+v", format, i, got, want)
+			}
+		}
+	}
+}
@@ -1,17 +1,28 @@
-package main
+// Package geom provides triangle meshes, ray intersection, and mesh
+// file formats.
+package geom
 
-import "gonum.org/v1/gonum/spatial/r3"
+import (
+	"math"
 
+	"gonum.org/v1/gonum/spatial/r3"
+)
+
+// A Mesh is a triangle mesh. Each triangle is a triple of indexes into
+// Verts.
 type Mesh struct {
 	Verts [][3]float64
 	Tris  [][3]int
 }
 
+// A Ray is a half-line from Origin in direction Dir.
 type Ray struct {
 	Origin r3.Vec
 	Dir    r3.Vec // Must be normalized
 }
 
+// IntersectMesh returns the distance along r to the nearest triangle of
+// m that r intersects, and whether there is any such triangle.
 func (r *Ray) IntersectMesh(m *Mesh) (t float64, ok bool) {
 	var tri r3.Triangle
 	var minT float64
@@ -32,6 +43,8 @@ func (r *Ray) IntersectMesh(m *Mesh) (t float64, ok bool) {
 	return minT, haveMin
 }
 
+// IntersectTriangle returns the distance along r to tri, and whether r
+// intersects tri.
 func (r *Ray) IntersectTriangle(tri *r3.Triangle) (t float64, ok bool) {
 	// Möller–Trumbore intersection, based on Wikipedia implementation
 	// and the Scratchapixel implementation.
@@ -66,6 +79,22 @@ func (r *Ray) IntersectTriangle(tri *r3.Triangle) (t float64, ok bool) {
 	return t, true
 }
 
+// Along returns the point at distance t along r.
 func (r *Ray) Along(t float64) r3.Vec {
 	return r3.Add(r.Origin, r3.Scale(t, r.Dir))
 }
+
+// Bounds returns the minimum and maximum coordinates of the vertexes
+// of m.
+func (m *Mesh) Bounds() (min, max r3.Vec) {
+	for i, v := range m.Verts {
+		p := r3.Vec{X: v[0], Y: v[1], Z: v[2]}
+		if i == 0 {
+			min, max = p, p
+			continue
+		}
+		min = r3.Vec{X: math.Min(min.X, p.X), Y: math.Min(min.Y, p.Y), Z: math.Min(min.Z, p.Z)}
+		max = r3.Vec{X: math.Max(max.X, p.X), Y: math.Max(max.Y, p.Y), Z: math.Max(max.Z, p.Z)}
+	}
+	return
+}
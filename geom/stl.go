@@ -1,4 +1,4 @@
-package main
+package geom
 
 import (
 	"bytes"
@@ -9,11 +9,14 @@ import (
 	"strings"
 )
 
+// An STLMesh is a mesh read from an STL file.
 type STLMesh struct {
 	Header string
 	Mesh
 }
 
+// ReadSTL reads a binary STL file from r. Vertexes shared by multiple
+// triangles are merged.
 func ReadSTL(r io.Reader) (*STLMesh, error) {
 	m := new(STLMesh)
 
@@ -22,7 +25,7 @@ func ReadSTL(r io.Reader) (*STLMesh, error) {
 		NTri uint32
 	}
 	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("reading STL header: %w", err)
 	}
 	m.Header = strings.TrimRight(string(header.H[:]), " ")
 
@@ -34,7 +37,7 @@ func ReadSTL(r io.Reader) (*STLMesh, error) {
 	for i := 0; i < int(header.NTri); i++ {
 		// Read a triangle
 		if _, err := io.ReadFull(r, triBuf); err != nil {
-			return nil, err
+			return nil, fmt.Errorf("reading STL triangle %d of %d: %w", i+1, header.NTri, err)
 		}
 		// Read the vertexes.
 		for v := range tri {
@@ -59,6 +62,8 @@ func ReadSTL(r io.Reader) (*STLMesh, error) {
 	return m, nil
 }
 
+// ToPOV writes m to w as a POV-Ray mesh2 object. POV-Ray's Y axis is
+// up, so this swaps the Y and Z coordinates.
 func (m *Mesh) ToPOV(w io.Writer) error {
 	var buf bytes.Buffer
 	fmt.Fprintf(&buf, "mesh2 {\n")
@@ -2,6 +2,13 @@ module github.com/aclements/shade
 
 go 1.18
 
+require (
+	github.com/sixdouglas/suncalc v0.0.0-20210131155613-475bb71c60c4
+	golang.org/x/image v0.0.0-20220902085622-e7cb96979f69
+	gonum.org/v1/gonum v0.12.0
+	gonum.org/v1/plot v0.12.0
+)
+
 require (
 	git.sr.ht/~sbinet/gg v0.3.1 // indirect
 	github.com/ajstarks/svgo v0.0.0-20211024235047-1546f124cd8b // indirect
@@ -9,9 +16,5 @@ require (
 	github.com/go-latex/latex v0.0.0-20210823091927-c0d11ff05a81 // indirect
 	github.com/go-pdf/fpdf v0.6.0 // indirect
 	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0 // indirect
-	github.com/sixdouglas/suncalc v0.0.0-20210131155613-475bb71c60c4 // indirect
-	golang.org/x/image v0.0.0-20220902085622-e7cb96979f69 // indirect
 	golang.org/x/text v0.3.7 // indirect
-	gonum.org/v1/gonum v0.12.0 // indirect
-	gonum.org/v1/plot v0.12.0 // indirect
 )
@@ -1,11 +1,21 @@
-package main
+// Package shade models the sun exposure of points in a 3D model of
+// buildings and foliage over time.
+package shade
 
 import (
-	"image/color"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
-	"gonum.org/v1/plot"
+	"github.com/aclements/shade/geom"
+	"github.com/aclements/shade/solar"
+	"gonum.org/v1/gonum/spatial/r3"
 )
 
 // A ShadeModel computes the sun exposure on a test point in a 3D model.
@@ -21,7 +31,7 @@ type ShadeModel struct {
 
 	elevationFeet float64
 
-	layers []*shadeLayer
+	layers []*Layer
 }
 
 // NewShadeModel returns a shade model where the origin is at the given
@@ -36,133 +46,392 @@ func NewShadeModel(latitude, longitude float64, elevationFeet float64) *ShadeMod
 	}
 }
 
-type shadeLayer struct {
-	mesh *Mesh
+// Latitude returns the latitude of m's origin in degrees.
+func (m *ShadeModel) Latitude() float64 { return m.lat }
 
-	// transmissivity returns the transmissivity of this layer on the
-	// given date in a range of 0 to 1. For a fully opaque layer, this
-	// returns 0. For foliage, this varies over the year.
-	transmissivity func(date time.Time) float64
+// Longitude returns the longitude of m's origin in degrees.
+func (m *ShadeModel) Longitude() float64 { return m.lon }
 
-	foliage bool
+// ElevationFeet returns the elevation of m's origin in feet.
+func (m *ShadeModel) ElevationFeet() float64 { return m.elevationFeet }
+
+// A Layer is a mesh of buildings or foliage in a ShadeModel.
+type Layer struct {
+	// Name identifies this layer in scenarios. It defaults to the base
+	// name of the STL file.
+	Name string
+
+	Mesh *geom.Mesh
+
+	// Foliage indicates this layer lets through a varying amount of
+	// sun over the year. Otherwise, it is opaque, like a building.
+	Foliage bool
+
+	// Disabled indicates this layer should not be included in the
+	// analysis.
+	Disabled bool
+}
+
+// Transmissivity returns the transmissivity of l on the given date in a
+// range of 0 to 1. For a fully opaque layer, this returns 0. For
+// foliage, this varies over the year.
+func (l *Layer) Transmissivity(date time.Time) float64 {
+	if !l.Foliage {
+		return 0
+	}
+	return foliageTransmissivity(date)
 }
 
+// AddBuildings adds an opaque layer from the binary STL file stlPath.
+// The layer is named after the file, without its extension.
 func (m *ShadeModel) AddBuildings(stlPath string) error {
-	return m.addLayer(stlPath, func(time.Time) float64 { return 0 }, false)
+	return m.addLayerFile(stlPath, false)
 }
 
+// AddFoliage is like AddBuildings, but adds a foliage layer.
 func (m *ShadeModel) AddFoliage(stlPath string) error {
-	trans := func(date time.Time) float64 {
-		// Based on Transmissivity of solar radiation through crowns of
-		// single urban trees—application for outdoor thermal comfort
-		// modelling. Konarska, et al.
-		//
-		// Foliated and defoliated trees have ~5% and ~50%
-		// transmissivity, respectively. Use the meteorological seasons
-		// to interpolate between these.
-		//
-		// TODO: This assumes northern hemisphere, and mid-latitudes at
-		// that.
-		day := date.YearDay()
-		const (
-			// Assume a normal year. This is all approximate anyway.
-			Feb28 = 59
-			May31 = 151
-			Aug31 = 243
-			Nov30 = 334
-		)
-		switch {
-		default: // Winter
-			fallthrough
-		case day <= Feb28: // Winter
-			return 0.5
-		case day <= May31: // Spring
-			return 0.5 + float64(day-Feb28)/(May31-Feb28)*(0.05-0.5)
-		case day <= Aug31: // Summer
-			return 0.05
-		case day <= Nov30: // Fall
-			return 0.05 + float64(day-Aug31)/(Nov30-Aug31)*(0.5-0.05)
-		}
+	return m.addLayerFile(stlPath, true)
+}
+
+// AddLayer adds a layer called name from the binary STL mesh read from
+// r. If foliage is true, the layer is foliage that lets through a
+// varying amount of sun over the year. Otherwise, it is opaque, like a
+// building.
+func (m *ShadeModel) AddLayer(name string, r io.Reader, foliage bool) error {
+	mesh, err := geom.ReadSTL(r)
+	if err != nil {
+		return fmt.Errorf("layer %s: %w", name, err)
 	}
-	return m.addLayer(stlPath, trans, true)
+	m.AddMesh(name, &mesh.Mesh, foliage)
+	return nil
 }
 
-func (m *ShadeModel) addLayer(stlPath string, trans func(time.Time) float64, foliage bool) error {
+// AddMesh adds a layer called name with the given mesh. foliage is as
+// for AddLayer.
+func (m *ShadeModel) AddMesh(name string, mesh *geom.Mesh, foliage bool) {
+	m.layers = append(m.layers, &Layer{Name: name, Mesh: mesh, Foliage: foliage})
+}
+
+func foliageTransmissivity(date time.Time) float64 {
+	// Based on Transmissivity of solar radiation through crowns of
+	// single urban trees—application for outdoor thermal comfort
+	// modelling. Konarska, et al.
+	//
+	// Foliated and defoliated trees have ~5% and ~50%
+	// transmissivity, respectively. Use the meteorological seasons
+	// to interpolate between these.
+	//
+	// TODO: This assumes northern hemisphere, and mid-latitudes at
+	// that.
+	day := date.YearDay()
+	const (
+		// Assume a normal year. This is all approximate anyway.
+		Feb28 = 59
+		May31 = 151
+		Aug31 = 243
+		Nov30 = 334
+	)
+	switch {
+	default: // Winter
+		fallthrough
+	case day <= Feb28: // Winter
+		return 0.5
+	case day <= May31: // Spring
+		return 0.5 + float64(day-Feb28)/(May31-Feb28)*(0.05-0.5)
+	case day <= Aug31: // Summer
+		return 0.05
+	case day <= Nov30: // Fall
+		return 0.05 + float64(day-Aug31)/(Nov30-Aug31)*(0.5-0.05)
+	}
+}
+
+func (m *ShadeModel) addLayerFile(stlPath string, foliage bool) error {
 	f, err := os.Open(stlPath)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
-	mesh, err := ReadSTL(f)
-	if err != nil {
-		return err
+	name := strings.TrimSuffix(filepath.Base(stlPath), filepath.Ext(stlPath))
+	return m.AddLayer(name, f, foliage)
+}
+
+// LayerNames returns the names of all layers in m, including disabled
+// layers.
+func (m *ShadeModel) LayerNames() []string {
+	var names []string
+	for _, l := range m.layers {
+		names = append(names, l.Name)
+	}
+	return names
+}
+
+// Layers returns all layers of m, including disabled layers.
+func (m *ShadeModel) Layers() []*Layer {
+	return m.layers
+}
+
+// SetLayerEnabled enables or disables the named layer. Disabled layers
+// are ignored by all analyses. This is useful for loading alternate
+// geometry that is only enabled by a Scenario.
+func (m *ShadeModel) SetLayerEnabled(name string, enabled bool) error {
+	l := m.Layer(name)
+	if l == nil {
+		return fmt.Errorf("unknown layer %q", name)
+	}
+	l.Disabled = !enabled
+	return nil
+}
+
+// Layer returns the layer of m called name, or nil if there is no such
+// layer.
+func (m *ShadeModel) Layer(name string) *Layer {
+	for _, l := range m.layers {
+		if l.Name == name {
+			return l
+		}
 	}
-	m.layers = append(m.layers, &shadeLayer{&mesh.Mesh, trans, foliage})
 	return nil
 }
 
+// ActiveLayers returns the enabled layers of m.
+func (m *ShadeModel) ActiveLayers() []*Layer {
+	var out []*Layer
+	for _, l := range m.layers {
+		if !l.Disabled {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// Clone returns a copy of m whose layers can be added, enabled, and
+// disabled without affecting m. The copy shares meshes with m.
+func (m *ShadeModel) Clone() *ShadeModel {
+	m2 := *m
+	m2.layers = make([]*Layer, len(m.layers))
+	for i, l := range m.layers {
+		l2 := *l
+		m2.layers[i] = &l2
+	}
+	return &m2
+}
+
+// Bounds returns the bounding box of all active layers of m. If there
+// are no active layers, it returns a zero bounding box.
+func (m *ShadeModel) Bounds() (min, max r3.Vec) {
+	for i, l := range m.ActiveLayers() {
+		lMin, lMax := l.Mesh.Bounds()
+		if i == 0 {
+			min, max = lMin, lMax
+			continue
+		}
+		min = r3.Vec{X: math.Min(min.X, lMin.X), Y: math.Min(min.Y, lMin.Y), Z: math.Min(min.Z, lMin.Z)}
+		max = r3.Vec{X: math.Max(max.X, lMax.X), Y: math.Max(max.Y, lMax.Y), Z: math.Max(max.Z, lMax.Z)}
+	}
+	return
+}
+
+// An IntensityOverTime is the sun light on a test point at regular
+// time steps.
 type IntensityOverTime struct {
-	sunPos []SunLight
+	sunPos []solar.SunLight
 
 	elevationFeet float64
 	increment     time.Duration
 }
 
-func (m *ShadeModel) IntensityOverYear(year int, testPos [3]float64) *IntensityOverTime {
+// NewIntensityOverTime returns an IntensityOverTime of the sun light
+// series, where each step is increment after the previous and the test
+// point is at elevationFeet.
+func NewIntensityOverTime(series []solar.SunLight, elevationFeet float64, increment time.Duration) *IntensityOverTime {
+	return &IntensityOverTime{series, elevationFeet, increment}
+}
+
+// Series returns the sun light at each time step of o.
+func (o *IntensityOverTime) Series() []solar.SunLight { return o.sunPos }
+
+// ElevationFeet returns the elevation of o's test point in feet.
+func (o *IntensityOverTime) ElevationFeet() float64 { return o.elevationFeet }
+
+// Increment returns the time between steps of o.
+func (o *IntensityOverTime) Increment() time.Duration { return o.increment }
+
+// IntensityOverYear computes the sun exposure at testPos every minute
+// of year, which starts and ends at midnight in loc. It is otherwise
+// like IntensityOverPeriod.
+func (m *ShadeModel) IntensityOverYear(ctx context.Context, year int, loc *time.Location, testPos [3]float64) (*IntensityOverTime, error) {
+	start := time.Date(year, 1, 1, 0, 0, 0, 0, loc)
+	return m.IntensityOverPeriod(ctx, start, start.AddDate(1, 0, 0), time.Minute, testPos)
+}
+
+// IntensityOverPeriod computes the sun exposure at testPos at each
+// increment from start up to, but not including, end.
+//
+// If ctx is cancelled, IntensityOverPeriod saves the results so far to
+// the cache and returns ctx's error. Running the same analysis again
+// resumes where it left off. Use WithProgress to monitor the analysis.
+func (m *ShadeModel) IntensityOverPeriod(ctx context.Context, start, end time.Time, increment time.Duration, testPos [3]float64) (*IntensityOverTime, error) {
+	times := timeSteps(start, end, increment)
+	ck, err := m.analysisKey(testPos, times)
+	if err != nil {
+		return nil, err
+	}
+	var sunPos []solar.SunLight
+	if ck.load(&sunPos) {
+		if progress := progressFunc(ctx); progress != nil {
+			progress(Progress{Traced: len(times), Total: len(times)})
+		}
+	} else {
+		partial, err := makeCacheKey("partial", ck.String())
+		if err != nil {
+			return nil, err
+		}
+		sunPos, err = m.computeSunLight(ctx, testPos, times, partial)
+		if err != nil {
+			return nil, err
+		}
+		// The cache is only an optimization, so don't throw away the
+		// result if we can't save it.
+		if err := ck.save(sunPos); err != nil {
+			log.Print(err)
+		}
+		partial.remove()
+	}
+
+	return &IntensityOverTime{sunPos, m.elevationFeet, increment}, nil
+}
+
+// AnalysisKey returns a string that identifies the result of
+// IntensityOverPeriod with the same arguments on m in its current
+// state. Analyses with the same key have the same result.
+func (m *ShadeModel) AnalysisKey(start, end time.Time, increment time.Duration, testPos [3]float64) (string, error) {
+	ck, err := m.analysisKey(testPos, timeSteps(start, end, increment))
+	if err != nil {
+		return "", err
+	}
+	return ck.String(), nil
+}
+
+// analysisKey returns the cache key of the sun light at testPos at each
+// of times on m.
+func (m *ShadeModel) analysisKey(testPos [3]float64, times []time.Time) (*cacheKey, error) {
+	// TODO: Maybe include source of computeSunLight and related
+	// functions in cacheKey?
+	var meshes []*geom.Mesh
+	var foliage []bool
+	for _, l := range m.ActiveLayers() {
+		meshes = append(meshes, l.Mesh)
+		foliage = append(foliage, l.Foliage)
+	}
+	return makeCacheKey(meshes, foliage, m.lat, m.lon, m.elevationFeet, testPos, times)
+}
+
+// timeSteps returns the times every increment from start up to, but not
+// including, end.
+func timeSteps(start, end time.Time, increment time.Duration) []time.Time {
 	var times []time.Time
-	t := time.Date(year, 1, 1, 0, 0, 0, 0, time.Local)
-	increment := time.Minute
-	for t.Year() == year {
+	for t := start; t.Before(end); t = t.Add(increment) {
 		times = append(times, t)
-		t = t.Add(increment)
+	}
+	return times
+}
+
+// Intensity returns the global intensity of sun in W/m².
+func (o *IntensityOverTime) Intensity(sun solar.SunLight) float64 {
+	return sun.GlobalIntensity(o.elevationFeet)
+}
+
+// progressInterval is the number of time steps computeSunLight traces
+// between progress reports and checks for cancellation.
+const progressInterval = 1000
+
+// checkpointInterval is how often computeSunLight saves its partial
+// results.
+const checkpointInterval = time.Minute
+
+// computeSunLight traces the sun light at testPos at each of times. It
+// resumes from and periodically saves partial results under the cache
+// key partial, but keeps going if it can't save them. If ctx is
+// cancelled, it saves its partial results and returns ctx's error, or
+// an error wrapping it if it can't save them.
+func (m *ShadeModel) computeSunLight(ctx context.Context, testPos [3]float64, times []time.Time, partial *cacheKey) ([]solar.SunLight, error) {
+	// TODO: This could be much more efficient. Do traces in parallel
+	// and since I only care about hit tests for this, not exact
+	// intersection point, add a fast path that caches the last triangle
+	// intersection and retests just that triangle on the next ray.
+	layers := m.ActiveLayers()
+	light := make([]solar.SunLight, 0, len(times))
+	var resume []solar.SunLight
+	if partial.load(&resume) && len(resume) <= len(times) {
+		light = append(light, resume...)
 	}
 
-	// TODO: Maybe include source of computeSunLight and related
-	// functions in CacheKey?
-	var meshes []*Mesh
-	for _, l := range m.layers {
-		meshes = append(meshes, l.mesh)
-	}
-	ck := MakeCacheKey(meshes, m.lat, m.lon, testPos, times)
-	var sunPos []SunLight
-	if !ck.Load(&sunPos) {
-		sunPos = m.computeSunLight(testPos, times)
-		ck.Save(sunPos)
-	}
-
-	return &IntensityOverTime{sunPos, m.elevationFeet, increment}
-}
-
-func (o *IntensityOverTime) newPlot() *plot.Plot {
-	plt := plot.New()
-	plt.Legend.Top = true
-	plt.Legend.Padding = 0.5 * plt.Legend.TextStyle.Font.Size
-
-	plt.BackgroundColor = color.Black
-	for _, elt := range []*color.Color{
-		&plt.Title.TextStyle.Color,
-		&plt.X.Color,
-		&plt.X.Tick.Color,
-		&plt.X.Tick.Label.Color,
-		&plt.X.Label.TextStyle.Color,
-		&plt.Y.Color,
-		&plt.Y.Tick.Color,
-		&plt.Y.Tick.Label.Color,
-		&plt.Y.Label.TextStyle.Color,
-		&plt.Legend.TextStyle.Color,
-	} {
-		*elt = color.White
-	}
-	return plt
-}
-
-// splitTime splits t into day and time of day. For the day, we put it
-// at noon to "center" it on that date. We put the result in UTC since
-// that's the time zone gonum will render it in and it avoids further
-// complications with DST. Time of day is returned as a duration since
-// midnight.
-func splitTime(t time.Time) (day time.Time, tod time.Duration) {
-	day = time.Date(t.Year(), t.Month(), t.Day(), 12, 0, 0, 0, time.UTC)
-	tod = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())*time.Nanosecond
-	return
+	progress := progressFunc(ctx)
+	start, resumed := time.Now(), len(light)
+	lastSave, checkpoint := start, true
+	for len(light) < len(times) {
+		if err := ctx.Err(); err != nil {
+			if saveErr := partial.save(light); saveErr != nil {
+				return nil, fmt.Errorf("%w (saving partial results: %v)", err, saveErr)
+			}
+			return nil, err
+		}
+
+		end := len(light) + progressInterval
+		if end > len(times) {
+			end = len(times)
+		}
+		for _, t := range times[len(light):end] {
+			light = append(light, TraceSun(layers, solar.GetSunPos(t, m.lat, m.lon), testPos))
+		}
+
+		if progress != nil {
+			p := Progress{Traced: len(light), Total: len(times)}
+			if elapsed := time.Since(start); p.Traced < p.Total {
+				rate := float64(p.Traced-resumed) / float64(elapsed)
+				p.ETA = time.Duration(float64(p.Total-p.Traced) / rate)
+			}
+			progress(p)
+		}
+		if checkpoint && len(light) < len(times) && time.Since(lastSave) >= checkpointInterval {
+			// Checkpoints are best-effort. If one fails, keep going
+			// without them.
+			if err := partial.save(light); err != nil {
+				log.Printf("%s; disabling checkpoints", err)
+				checkpoint = false
+			}
+			lastSave = time.Now()
+		}
+	}
+	return light, nil
+}
+
+// TraceSun computes the sun light reaching pos through layers when the
+// sun is at sunPos.
+func TraceSun(layers []*Layer, sunPos solar.SunPos, pos [3]float64) solar.SunLight {
+	out := solar.SunLight{SunPos: sunPos}
+	if sunPos.Altitude < 0 {
+		return out
+	}
+
+	sunRay := sunPos.Ray(pos)
+	light := 1.0
+	building, foliage := false, false
+	for _, l := range layers {
+		tRay, hit := sunRay.IntersectMesh(l.Mesh)
+		_ = tRay
+		if hit && light != 0 {
+			light *= l.Transmissivity(sunPos.T)
+		}
+		if hit {
+			if l.Foliage {
+				foliage = true
+			} else {
+				building = true
+			}
+		}
+	}
+	out.Light = light
+	out.Foliage = foliage && !building
+	return out
 }
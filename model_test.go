@@ -0,0 +1,64 @@
+package shade
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aclements/shade/geom"
+)
+
+// chdirTemp changes to a temporary directory for the rest of the test,
+// since IntensityOverPeriod caches in the current directory.
+func chdirTemp(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+// southWall returns a wall south of the origin that blocks the low
+// winter sun.
+func southWall() *geom.Mesh {
+	return &geom.Mesh{
+		Verts: [][3]float64{{-100, -10, 0}, {100, -10, 0}, {100, -10, 20}, {-100, -10, 20}},
+		Tris:  [][3]int{{0, 1, 2}, {0, 2, 3}},
+	}
+}
+
+func TestIntensityCacheKey(t *testing.T) {
+	chdirTemp(t)
+
+	// The same mesh as a building and as foliage must not share a
+	// cached result.
+	start := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 1)
+	pos := [3]float64{0, 0, 1}
+	var got [2]*IntensityOverTime
+	for i, foliage := range []bool{false, true} {
+		m := NewShadeModel(42.4195011, -71.2064993, 200)
+		m.AddMesh("wall", southWall(), foliage)
+		o, err := m.IntensityOverPeriod(context.Background(), start, end, 10*time.Minute, pos)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[i] = o
+	}
+	var building, foliage int
+	for i := range got[0].Series() {
+		if got[0].Series()[i].Foliage {
+			building++
+		}
+		if got[1].Series()[i].Foliage {
+			foliage++
+		}
+	}
+	if building != 0 || foliage == 0 {
+		t.Errorf("want foliage shade only from foliage layer, got %d steps from building and %d from foliage", building, foliage)
+	}
+}
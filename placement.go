@@ -0,0 +1,259 @@
+package shade
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aclements/shade/geom"
+	"gonum.org/v1/gonum/spatial/r3"
+)
+
+// An Orientation is the direction a surface faces.
+type Orientation struct {
+	// Tilt is the angle of the surface from horizontal, in degrees.
+	Tilt float64
+
+	// Azimuth is the direction the surface faces, in degrees, where 0
+	// is north and 90 is east.
+	Azimuth float64
+}
+
+// An Objective scores candidate placements.
+type Objective struct {
+	Name string
+
+	// Score returns the score of a placement that receives sun light
+	// o. Higher scores are better. If the objective depends on the
+	// orientation of the placement, it also returns the best
+	// orientation.
+	Score func(o *IntensityOverTime) (score float64, orient Orientation)
+}
+
+// MaxAnnualPV returns an Objective that maximizes the annual energy
+// production of panel, searching over all combinations of the given
+// tilts and azimuths. If tilts or azimuths is nil, it uses a default
+// range of south-ish facing orientations (for the northern hemisphere).
+func MaxAnnualPV(panel PVPanel, tilts, azimuths []float64) Objective {
+	if tilts == nil {
+		tilts = []float64{0, 10, 20, 30, 40, 50, 60}
+	}
+	if azimuths == nil {
+		azimuths = []float64{90, 120, 150, 180, 210, 240, 270}
+	}
+	return Objective{
+		Name: "Annual PV energy (kWh)",
+		Score: func(o *IntensityOverTime) (float64, Orientation) {
+			best, bestOrient := math.Inf(-1), Orientation{}
+			for _, tilt := range tilts {
+				for _, az := range azimuths {
+					p := panel
+					p.Tilt, p.Azimuth = tilt, az
+					if kWh := p.Yield(o).Annual.Shaded; kWh > best {
+						best, bestOrient = kWh, Orientation{tilt, az}
+					}
+					if tilt == 0 {
+						// Azimuth doesn't matter.
+						break
+					}
+				}
+			}
+			return best, bestOrient
+		},
+	}
+}
+
+// MaxGrowingSeasonSun returns an Objective that maximizes the hours of
+// direct sun over the growing season.
+func MaxGrowingSeasonSun(season GrowingSeason) Objective {
+	return Objective{
+		Name: "Growing season direct sun (h)",
+		Score: func(o *IntensityOverTime) (float64, Orientation) {
+			var d time.Duration
+			for _, t := range o.MonthlyTotals() {
+				if season.contains(t.Start.Month()) {
+					d += t.Direct
+				}
+			}
+			return d.Hours(), Orientation{}
+		},
+	}
+}
+
+// MinSummerAfternoonSun returns an Objective that minimizes the hours of
+// direct sun between noon and 6 PM from June through August. The score
+// is the negated number of hours.
+func MinSummerAfternoonSun() Objective {
+	return Objective{
+		Name: "Summer afternoon direct sun (negated h)",
+		Score: func(o *IntensityOverTime) (float64, Orientation) {
+			var d time.Duration
+			for _, sun := range o.sunPos {
+				if m := sun.T.Month(); m < time.June || m > time.August {
+					continue
+				}
+				if h := sun.T.Hour(); h < 12 || h >= 18 {
+					continue
+				}
+				if SunLevel(sun) == LevelDirect {
+					d += o.increment
+				}
+			}
+			return -d.Hours(), Orientation{}
+		},
+	}
+}
+
+// A PlacementSearch describes a search for the best placement of a
+// panel, garden bed, or similar within a region of a ShadeModel.
+type PlacementSearch struct {
+	// Region is a polygon in the X/Y plane to search within.
+	Region [][2]float64
+
+	// Height is the height of the placement above the surface of the
+	// model (for example, a roof) or above Z=0 if there is no surface.
+	Height float64
+
+	// Spacing is the distance between candidate positions in both X
+	// and Y.
+	Spacing float64
+
+	Year int
+
+	// Increment is the time step of the analysis of each candidate
+	// position. If 0, it defaults to 10 minutes, which is much faster
+	// than a full per-minute analysis and nearly as accurate.
+	Increment time.Duration
+
+	Objective Objective
+}
+
+// A Placement is a scored candidate position.
+type Placement struct {
+	Pos [3]float64
+	Orientation
+	Score float64
+}
+
+// SearchPlacements scores every candidate position in s and returns
+// them from best to worst. The year of s starts and ends at midnight in
+// loc.
+func (m *ShadeModel) SearchPlacements(ctx context.Context, s *PlacementSearch, loc *time.Location) ([]Placement, error) {
+	if len(s.Region) < 3 {
+		return nil, fmt.Errorf("region must have at least 3 vertexes")
+	}
+	if s.Spacing <= 0 {
+		return nil, fmt.Errorf("spacing must be positive")
+	}
+	increment := s.Increment
+	if increment == 0 {
+		increment = 10 * time.Minute
+	}
+	start := time.Date(s.Year, 1, 1, 0, 0, 0, 0, loc)
+	end := start.AddDate(1, 0, 0)
+
+	// Generate candidate positions.
+	var out []Placement
+	min, max := polygonBounds(s.Region)
+	for y := min[1] + s.Spacing/2; y < max[1]; y += s.Spacing {
+		for x := min[0] + s.Spacing/2; x < max[0]; x += s.Spacing {
+			if pointInPolygon([2]float64{x, y}, s.Region) {
+				z := m.surfaceHeight(x, y) + s.Height
+				out = append(out, Placement{Pos: [3]float64{x, y, z}})
+			}
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no candidate positions in region")
+	}
+
+	// Score candidates in parallel. The workers share ctx, so serialize
+	// their progress reports.
+	if f := progressFunc(ctx); f != nil {
+		var mu sync.Mutex
+		ctx = WithProgress(ctx, func(p Progress) {
+			mu.Lock()
+			defer mu.Unlock()
+			f(p)
+		})
+	}
+	var wg sync.WaitGroup
+	next := make(chan int)
+	errs := make([]error, len(out))
+	for i := 0; i < runtime.GOMAXPROCS(0); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				p := &out[i]
+				o, err := m.IntensityOverPeriod(ctx, start, end, increment, p.Pos)
+				if err != nil {
+					errs[i] = fmt.Errorf("candidate %v: %w", p.Pos, err)
+					continue
+				}
+				p.Score, p.Orientation = s.Objective.Score(o)
+			}
+		}()
+	}
+	for i := range out {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	return out, nil
+}
+
+// surfaceHeight returns the Z coordinate of the top surface of the
+// model at (x, y), or 0 if there is no surface there.
+func (m *ShadeModel) surfaceHeight(x, y float64) float64 {
+	_, max := m.Bounds()
+	ray := geom.Ray{Origin: r3.Vec{X: x, Y: y, Z: max.Z + 1}, Dir: r3.Vec{Z: -1}}
+	best, hit := 0.0, false
+	for _, l := range m.ActiveLayers() {
+		if l.Foliage {
+			continue
+		}
+		if t, ok := ray.IntersectMesh(l.Mesh); ok {
+			if z := ray.Along(t).Z; !hit || z > best {
+				best, hit = z, true
+			}
+		}
+	}
+	return best
+}
+
+func polygonBounds(poly [][2]float64) (min, max [2]float64) {
+	min, max = poly[0], poly[0]
+	for _, p := range poly[1:] {
+		for i := range p {
+			min[i] = math.Min(min[i], p[i])
+			max[i] = math.Max(max[i], p[i])
+		}
+	}
+	return
+}
+
+// pointInPolygon returns whether p is inside poly using the even-odd
+// rule.
+func pointInPolygon(p [2]float64, poly [][2]float64) bool {
+	in := false
+	for i, j := 0, len(poly)-1; i < len(poly); j, i = i, i+1 {
+		a, b := poly[i], poly[j]
+		if (a[1] > p[1]) != (b[1] > p[1]) &&
+			p[0] < (b[0]-a[0])*(p[1]-a[1])/(b[1]-a[1])+a[0] {
+			in = !in
+		}
+	}
+	return in
+}
@@ -0,0 +1,37 @@
+package shade
+
+import (
+	"context"
+	"time"
+)
+
+// Progress describes how far an analysis of a single test point has
+// gotten.
+type Progress struct {
+	// Traced is the number of time steps whose sun ray has been traced
+	// so far, including any resumed from a checkpoint. Total is the
+	// number of time steps in the analysis.
+	Traced, Total int
+
+	// ETA is the estimated time until the analysis finishes, or 0 if
+	// it's unknown or the analysis is done.
+	ETA time.Duration
+}
+
+type progressKey struct{}
+
+// WithProgress returns a copy of ctx that reports the progress of
+// analyses run with it to f. f is called periodically while tracing
+// sun rays, and once when an analysis finishes, including when its
+// result comes from the cache. f is never called concurrently, even by
+// analyses that run in parallel, such as SearchPlacements.
+func WithProgress(ctx context.Context, f func(Progress)) context.Context {
+	return context.WithValue(ctx, progressKey{}, f)
+}
+
+// progressFunc returns the progress function of ctx, or nil if there is
+// none.
+func progressFunc(ctx context.Context) func(Progress) {
+	f, _ := ctx.Value(progressKey{}).(func(Progress))
+	return f
+}
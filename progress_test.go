@@ -0,0 +1,121 @@
+package shade
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIntensityResume(t *testing.T) {
+	chdirTemp(t)
+
+	m := NewShadeModel(42.4195011, -71.2064993, 200)
+	m.AddMesh("wall", southWall(), false)
+	pos := [3]float64{0, 0, 1}
+	start := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 3)
+	total := int(end.Sub(start) / time.Minute)
+
+	// Cancel part way through.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx = WithProgress(ctx, func(p Progress) {
+		if p.Total != total {
+			t.Errorf("want total %d, got %d", total, p.Total)
+		}
+		if p.Traced >= 2*progressInterval {
+			cancel()
+		}
+	})
+	if _, err := m.IntensityOverPeriod(ctx, start, end, time.Minute, pos); !errors.Is(err, context.Canceled) {
+		t.Fatalf("want context.Canceled, got %v", err)
+	}
+
+	// Resume from the checkpoint.
+	var reports []Progress
+	ctx = WithProgress(context.Background(), func(p Progress) {
+		reports = append(reports, p)
+	})
+	o, err := m.IntensityOverPeriod(ctx, start, end, time.Minute, pos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) == 0 || reports[0].Traced != 3*progressInterval {
+		t.Errorf("want first report after resuming at %d, got %+v", 3*progressInterval, reports)
+	}
+	if last := reports[len(reports)-1]; last.Traced != total || last.ETA != 0 {
+		t.Errorf("want final report %d/%d with no ETA, got %+v", total, total, last)
+	}
+
+	// The resumed result should match a fresh computation.
+	ck, err := makeCacheKey("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := m.computeSunLight(context.Background(), pos, timeSteps(start, end, time.Minute), ck)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := o.Series()
+	if len(got) != len(want) {
+		t.Fatalf("want %d steps, got %d", len(want), len(got))
+	}
+	var shaded int
+	for i := range got {
+		if !got[i].T.Equal(want[i].T) || got[i].Light != want[i].Light || got[i].Altitude != want[i].Altitude {
+			t.Fatalf("step %d: want %+v, got %+v", i, want[i], got[i])
+		}
+		if got[i].Altitude > 0 && got[i].Light == 0 {
+			shaded++
+		}
+	}
+	if shaded == 0 {
+		t.Errorf("wall never shades the test point")
+	}
+
+	// A cached result reports that it's done.
+	reports = nil
+	if _, err := m.IntensityOverPeriod(ctx, start, end, time.Minute, pos); err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 1 || reports[0].Traced != total {
+		t.Errorf("want one final report for cached result, got %+v", reports)
+	}
+}
+
+func TestSearchPlacementsProgress(t *testing.T) {
+	chdirTemp(t)
+	// Make sure there are several workers, even on one CPU.
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	m := NewShadeModel(42.4195011, -71.2064993, 200)
+	m.AddMesh("wall", southWall(), false)
+	s := &PlacementSearch{
+		Region:    [][2]float64{{-2, -2}, {2, -2}, {2, 2}, {-2, 2}},
+		Height:    1,
+		Spacing:   1,
+		Year:      2022,
+		Increment: 2 * time.Hour,
+		Objective: Objective{Score: func(o *IntensityOverTime) (float64, Orientation) {
+			return 0, Orientation{}
+		}},
+	}
+	var active, reports int32
+	ctx := WithProgress(context.Background(), func(p Progress) {
+		if atomic.AddInt32(&active, 1) != 1 {
+			t.Errorf("progress called concurrently")
+		}
+		reports++
+		time.Sleep(time.Millisecond) // Give other workers a chance to overlap.
+		atomic.AddInt32(&active, -1)
+	})
+	if _, err := m.SearchPlacements(ctx, s, time.UTC); err != nil {
+		t.Fatal(err)
+	}
+	if reports < 16 {
+		t.Errorf("want a final report for each of 16 candidates, got %d reports", reports)
+	}
+}
@@ -0,0 +1,187 @@
+package shade
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aclements/shade/solar"
+	"gonum.org/v1/gonum/spatial/r3"
+)
+
+// A PVPanel describes a photovoltaic panel or array of panels.
+type PVPanel struct {
+	// Tilt is the angle of the panel from horizontal, in degrees.
+	Tilt float64
+
+	// Azimuth is the direction the panel faces, in degrees, where 0 is
+	// north and 90 is east.
+	Azimuth float64
+
+	// Area is the area of the panel, in m².
+	Area float64
+
+	// Efficiency is the efficiency of the panel at standard test
+	// conditions (a cell temperature of 25°C), between 0 and 1.
+	Efficiency float64
+
+	// TempCoeff is the relative change in efficiency per °C of cell
+	// temperature above 25°C. This is typically around -0.004.
+	TempCoeff float64
+
+	// NOCT is the nominal operating cell temperature in °C. If 0, it
+	// defaults to 45°C.
+	NOCT float64
+
+	// AmbientTemp returns the ambient air temperature in °C at time t.
+	// If nil, the ambient temperature is assumed to be 20°C.
+	AmbientTemp func(t time.Time) float64
+}
+
+// normal returns the unit normal vector of the panel's surface.
+func (p *PVPanel) normal() r3.Vec {
+	const deg2rad = math.Pi / 180
+	tilt, az := p.Tilt*deg2rad, p.Azimuth*deg2rad
+	return r3.Vec{
+		X: math.Sin(tilt) * math.Sin(az),
+		Y: math.Sin(tilt) * math.Cos(az),
+		Z: math.Cos(tilt),
+	}
+}
+
+// PlaneOfArray computes the irradiance on the surface of the panel
+// from sun, in W/m². This is the direct radiation projected on to the
+// panel, plus the diffuse radiation from the portion of the sky the
+// panel faces.
+func (p *PVPanel) PlaneOfArray(sun solar.SunLight, elevationFeet float64) float64 {
+	direct := sun.DirectIntensity(elevationFeet)
+	if direct == 0 {
+		return 0
+	}
+	ray := sun.Ray([3]float64{})
+	cosIncidence := r3.Dot(ray.Dir, p.normal())
+	if cosIncidence < 0 {
+		// The sun is behind the panel.
+		cosIncidence = 0
+	}
+	// As in GlobalIntensity, diffuse radiation is ~10% of direct
+	// radiation. Assume it's isotropic.
+	skyView := (1 + math.Cos(p.Tilt*(math.Pi/180))) / 2
+	return sun.Light*direct*cosIncidence + 0.1*direct*skyView
+}
+
+// power computes the electrical power output of the panel in W.
+func (p *PVPanel) power(sun solar.SunLight, elevationFeet float64) float64 {
+	poa := p.PlaneOfArray(sun, elevationFeet)
+	if poa == 0 {
+		return 0
+	}
+	noct, ambient := p.NOCT, 20.0
+	if noct == 0 {
+		noct = 45
+	}
+	if p.AmbientTemp != nil {
+		ambient = p.AmbientTemp(sun.T)
+	}
+	// Standard NOCT cell temperature model: NOCT is measured at 800
+	// W/m² and 20°C ambient.
+	cellTemp := ambient + (noct-20)/800*poa
+	eff := p.Efficiency * (1 + p.TempCoeff*(cellTemp-25))
+	return poa * p.Area * math.Max(eff, 0)
+}
+
+// PVEnergy is the energy produced by a panel over a period of time.
+type PVEnergy struct {
+	// Start and End are the bounds of this period. End is exclusive.
+	Start, End time.Time
+
+	// Shaded is the energy produced accounting for shade, in kWh.
+	Shaded float64
+
+	// Unshaded is the energy that would be produced with no shade, in
+	// kWh.
+	Unshaded float64
+}
+
+// ShadingLoss returns the fraction of energy lost to shade.
+func (e PVEnergy) ShadingLoss() float64 {
+	if e.Unshaded == 0 {
+		return 0
+	}
+	return 1 - e.Shaded/e.Unshaded
+}
+
+// A PVYield is the estimated energy production of a panel at one
+// location.
+type PVYield struct {
+	Location [3]float64
+	Annual   PVEnergy
+	Monthly  []PVEnergy
+}
+
+// Yield estimates the energy production of p from the sun light in o.
+func (p *PVPanel) Yield(o *IntensityOverTime) *PVYield {
+	y := new(PVYield)
+	for _, day := range o.byDay() {
+		yr, mo, d := day[0].T.Date()
+		start := time.Date(yr, mo, d, 0, 0, 0, 0, day[0].T.Location())
+		if len(y.Monthly) == 0 || y.Monthly[len(y.Monthly)-1].Start.Month() != mo {
+			y.Monthly = append(y.Monthly, PVEnergy{Start: start})
+		}
+		e := &y.Monthly[len(y.Monthly)-1]
+		e.End = start.AddDate(0, 0, 1)
+		for _, sun := range day {
+			hours := o.increment.Hours()
+			e.Shaded += p.power(sun, o.elevationFeet) * hours / 1000
+			unshaded := sun
+			unshaded.Light, unshaded.Foliage = 1, false
+			e.Unshaded += p.power(unshaded, o.elevationFeet) * hours / 1000
+		}
+	}
+	if len(y.Monthly) > 0 {
+		y.Annual.Start = y.Monthly[0].Start
+		y.Annual.End = y.Monthly[len(y.Monthly)-1].End
+	}
+	for _, e := range y.Monthly {
+		y.Annual.Shaded += e.Shaded
+		y.Annual.Unshaded += e.Unshaded
+	}
+	return y
+}
+
+// PVYield estimates the energy production over year in loc of panel p
+// placed at each of positions.
+func (m *ShadeModel) PVYield(ctx context.Context, year int, loc *time.Location, p *PVPanel, positions [][3]float64) ([]*PVYield, error) {
+	var out []*PVYield
+	for _, pos := range positions {
+		o, err := m.IntensityOverYear(ctx, year, loc, pos)
+		if err != nil {
+			return nil, fmt.Errorf("position %v: %w", pos, err)
+		}
+		y := p.Yield(o)
+		y.Location = pos
+		out = append(out, y)
+	}
+	return out, nil
+}
+
+// WritePVReport writes a table of the annual and monthly energy
+// production at each panel location to w.
+func WritePVReport(w io.Writer, yields []*PVYield) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "Location\tPeriod\tkWh\tUnshaded kWh\tShading loss\t\n")
+	for _, y := range yields {
+		loc := fmt.Sprintf("(%g, %g, %g)", y.Location[0], y.Location[1], y.Location[2])
+		row := func(period string, e PVEnergy) {
+			fmt.Fprintf(tw, "%s\t%s\t%.1f\t%.1f\t%.1f%%\t\n", loc, period, e.Shaded, e.Unshaded, 100*e.ShadingLoss())
+		}
+		for _, e := range y.Monthly {
+			row(e.Start.Format("Jan"), e)
+		}
+		row("Annual", y.Annual)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,30 @@
+package shade
+
+import (
+	"math"
+	"testing"
+
+	"github.com/aclements/shade/solar"
+)
+
+func TestPlaneOfArray(t *testing.T) {
+	sun := solar.SunLight{Light: 1, SunPos: solar.SunPos{Altitude: 30, Azimuth: 180}}
+	direct := sun.DirectIntensity(0)
+
+	for _, test := range []struct {
+		name  string
+		panel PVPanel
+		want  float64
+	}{
+		// A panel facing straight at the sun gets all direct radiation.
+		{"facing sun", PVPanel{Tilt: 60, Azimuth: 180}, direct + 0.1*direct*(1+math.Cos(60*math.Pi/180))/2},
+		// A horizontal panel gets the direct radiation projected down.
+		{"horizontal", PVPanel{Tilt: 0}, direct*0.5 + 0.1*direct},
+		// A panel facing away from the sun only gets diffuse radiation.
+		{"facing away", PVPanel{Tilt: 90, Azimuth: 0}, 0.1 * direct / 2},
+	} {
+		if got := test.panel.PlaneOfArray(sun, 0); math.Abs(got-test.want) > 1e-6 {
+			t.Errorf("POA %s: want %v, got %v", test.name, test.want, got)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+	"os"
+	"time"
+
+	"github.com/aclements/shade"
+	"github.com/aclements/shade/solar"
+)
+
+// An Animation is a sequence of renders of a model over time.
+type Animation struct {
+	TestPos, CameraOffset [3]float64
+
+	// Times are the times of each frame. See DaylightTimes and
+	// TimesOverYear.
+	Times []time.Time
+
+	// Width and Height are the size of each frame in pixels.
+	Width, Height int
+
+	// POVRay indicates to render frames using POV, which uses
+	// POV-Ray if it is installed. Otherwise, frames are rendered with
+	// Image, which is much faster.
+	POVRay bool
+}
+
+// DaylightTimes returns times every step over the day containing day
+// when the sun is above the horizon.
+func DaylightTimes(m *shade.ShadeModel, day time.Time, step time.Duration) []time.Time {
+	var times []time.Time
+	t := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	for end := t.AddDate(0, 0, 1); t.Before(end); t = t.Add(step) {
+		if solar.GetSunPos(t, m.Latitude(), m.Longitude()).Altitude > 0 {
+			times = append(times, t)
+		}
+	}
+	return times
+}
+
+// TimesOverYear returns the same time of day in loc every days days over
+// year, for showing how shadows change with the seasons.
+func TimesOverYear(year int, loc *time.Location, hour, min, days int) []time.Time {
+	var times []time.Time
+	for t := time.Date(year, 1, 1, hour, min, 0, 0, loc); t.Year() == year; t = t.AddDate(0, 0, days) {
+		times = append(times, t)
+	}
+	return times
+}
+
+// Frames renders each frame of a, with the time of each frame drawn at
+// the top of the frame.
+func Frames(m *shade.ShadeModel, a *Animation) ([]*image.RGBA, error) {
+	var frames []*image.RGBA
+	for _, t := range a.Times {
+		var img *image.RGBA
+		if a.POVRay {
+			var err error
+			if img, err = renderPOVFrame(m, a, t); err != nil {
+				return nil, fmt.Errorf("rendering frame at %s: %w", t.Format(time.RFC3339), err)
+			}
+		} else {
+			img = Image(m, a.TestPos, a.CameraOffset, t, a.Width, a.Height)
+		}
+		drawTimestamp(img, t)
+		frames = append(frames, img)
+	}
+	return frames, nil
+}
+
+// renderPOVFrame renders a frame of a like POV and reads back the
+// result. Unlike POV, it doesn't display the frame, so it can run
+// unattended.
+func renderPOVFrame(m *shade.ShadeModel, a *Animation, t time.Time) (*image.RGBA, error) {
+	f, err := os.CreateTemp("", "shade-*.png")
+	if err != nil {
+		return nil, err
+	}
+	path := f.Name()
+	f.Close()
+	defer os.Remove(path)
+
+	if err := pov(m, a.TestPos, a.CameraOffset, t, path, a.Width, a.Height, "-D"); err != nil { // Disable display
+		return nil, err
+	}
+
+	f, err = os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	src, err := png.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("reading rendered frame: %w", err)
+	}
+	img := image.NewRGBA(src.Bounds())
+	draw.Draw(img, img.Bounds(), src, src.Bounds().Min, draw.Src)
+	return img, nil
+}
+
+// drawTimestamp draws t in a banner across the top of img.
+func drawTimestamp(img *image.RGBA, t time.Time) {
+	const h = 20
+	b := img.Bounds()
+	banner := image.Rect(b.Min.X, b.Min.Y, b.Max.X, b.Min.Y+h)
+	draw.Draw(img, banner, image.NewUniform(color.RGBA{0, 0, 0, 0xa0}), image.Point{}, draw.Over)
+	drawText(img, (b.Min.X+b.Max.X)/2, b.Min.Y+h/2, t.Format("Mon Jan 2 2006 15:04 MST"), color.White)
+}
+
+// WriteGIF writes frames as an animated GIF that shows each frame for
+// delay.
+func WriteGIF(w io.Writer, frames []*image.RGBA, delay time.Duration) error {
+	anim := &gif.GIF{}
+	for _, frame := range frames {
+		p := image.NewPaletted(frame.Bounds(), palette.Plan9)
+		draw.FloydSteinberg.Draw(p, p.Bounds(), frame, frame.Bounds().Min)
+		anim.Image = append(anim.Image, p)
+		anim.Delay = append(anim.Delay, int(delay/(10*time.Millisecond)))
+	}
+	return gif.EncodeAll(w, anim)
+}
+
+// WriteFrames writes each frame as a PNG. pattern is a format string
+// for the path of each frame, such as "frame-%03d.png", which is passed
+// the frame number.
+func WriteFrames(pattern string, frames []*image.RGBA) error {
+	for i, frame := range frames {
+		if err := SavePNG(frame, fmt.Sprintf(pattern, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"time"
+
+	"github.com/aclements/shade"
+	"github.com/aclements/shade/solar"
+	"gonum.org/v1/gonum/spatial/r3"
+)
+
+// Fisheye renders a 180° hemispherical view of the sky from testPos,
+// showing the model's layers and the sun's paths over year in loc. It
+// uses an equidistant projection, where the center of the image is the
+// zenith and the edge of the circle is the horizon. Like a Solar Pathfinder
+// reflection and the chart.SunPath diagram, north is up and east is right.
+//
+// Unlike POV, this rasterizes the model in-process and does not
+// require POV-Ray.
+func Fisheye(m *shade.ShadeModel, year int, loc *time.Location, testPos [3]float64, size int) *image.RGBA {
+	img := newZImage(size, size)
+	c := float64(size) / 2
+	radius := c * 0.95
+	origin := r3.Vec{X: testPos[0], Y: testPos[1], Z: testPos[2]}
+	proj := func(alt, az float64) (x, y float64) {
+		const deg2rad = math.Pi / 180
+		r := radius * (90 - alt) / 90
+		return c + r*math.Sin(az*deg2rad), c - r*math.Cos(az*deg2rad)
+	}
+
+	// Fill the sky with a gradient from the horizon to the zenith.
+	horizon, zenith := color.RGBA{0xc8, 0xdc, 0xf0, 0xff}, color.RGBA{0x30, 0x60, 0xb0, 0xff}
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			r := math.Hypot(float64(x)+0.5-c, float64(y)+0.5-c) / radius
+			if r > 1 {
+				img.SetRGBA(x, y, color.RGBA{0, 0, 0, 0xff})
+				continue
+			}
+			img.SetRGBA(x, y, lerpRGBA(zenith, horizon, r))
+		}
+	}
+
+	// Rasterize the layers.
+	for _, l := range m.ActiveLayers() {
+		base := color.RGBA{0xc0, 0xc0, 0xc0, 0xff}
+		if l.Foliage {
+			base = color.RGBA{0x40, 0x90, 0x40, 0xff}
+		}
+		for _, idxs := range l.Mesh.Tris {
+			var tri [3]r3.Vec
+			for i, idx := range idxs {
+				v := l.Mesh.Verts[idx]
+				tri[i] = r3.Sub(r3.Vec{X: v[0], Y: v[1], Z: v[2]}, origin)
+			}
+			// Shade by the angle between the triangle and the view
+			// direction to give some depth cues.
+			n := r3.Unit(r3.Cross(r3.Sub(tri[1], tri[0]), r3.Sub(tri[2], tri[0])))
+			view := r3.Unit(r3.Add(r3.Add(tri[0], tri[1]), tri[2]))
+			shade := 0.4 + 0.6*math.Abs(r3.Dot(n, view))
+			col := color.RGBA{uint8(float64(base.R) * shade), uint8(float64(base.G) * shade), uint8(float64(base.B) * shade), 0xff}
+			fisheyeTriangle(img, tri, proj, col, 0)
+		}
+	}
+
+	// Mask out everything below the horizon.
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if math.Hypot(float64(x)+0.5-c, float64(y)+0.5-c) > radius {
+				img.SetRGBA(x, y, color.RGBA{0, 0, 0, 0xff})
+			}
+		}
+	}
+
+	// Draw the sun's paths.
+	tracks := solar.GetTracks(year, loc, m.Latitude(), m.Longitude())
+	drawTrack := func(track []solar.SunPos, width float64, col color.RGBA) {
+		for i := 1; i < len(track); i++ {
+			a, b := track[i-1], track[i]
+			if a.Altitude < 0 || b.Altitude < 0 {
+				continue
+			}
+			x0, y0 := proj(a.Altitude, a.Azimuth)
+			x1, y1 := proj(b.Altitude, b.Azimuth)
+			drawLine(img.RGBA, x0, y0, x1, y1, width, col)
+		}
+	}
+	thin := color.RGBA{0xff, 0xff, 0xff, 0xff}
+	for _, track := range tracks.Months {
+		drawTrack(track, 1, thin)
+	}
+	for _, h := range tracks.Hours {
+		drawTrack(h, 1, thin)
+	}
+	drawTrack(tracks.Summer, 3, color.RGBA{0xff, 0x60, 0x00, 0xff})
+	drawTrack(tracks.Equinox, 3, color.RGBA{0xff, 0xc0, 0x00, 0xff})
+	drawTrack(tracks.Winter, 3, color.RGBA{0x60, 0xa0, 0xff, 0xff})
+
+	for i, dir := range []string{"N", "E", "S", "W"} {
+		x, y := proj(-4, float64(i)*90)
+		drawText(img.RGBA, int(x), int(y), dir, color.White)
+	}
+	return img.RGBA
+}
+
+// fisheyeTriangle rasterizes tri, whose vertexes are relative to the
+// viewer. Since the fisheye projection is non-linear, this recursively
+// subdivides large triangles so straight edges curve correctly.
+func fisheyeTriangle(img *zImage, tri [3]r3.Vec, proj func(alt, az float64) (x, y float64), col color.RGBA, depth int) {
+	const (
+		maxAngle = 4 * math.Pi / 180 // Maximum angular size to rasterize directly
+		maxDepth = 6
+	)
+	// A planar triangle with all vertexes below the horizon is entirely
+	// below the horizon.
+	if tri[0].Z < 0 && tri[1].Z < 0 && tri[2].Z < 0 {
+		return
+	}
+
+	// Subdivide if the triangle spans too much of the view.
+	span := 0.0
+	for i := range tri {
+		a, b := r3.Unit(tri[i]), r3.Unit(tri[(i+1)%3])
+		span = math.Max(span, math.Acos(math.Max(-1, math.Min(1, r3.Dot(a, b)))))
+	}
+	if span > maxAngle && depth < maxDepth {
+		mid := func(a, b r3.Vec) r3.Vec { return r3.Scale(0.5, r3.Add(a, b)) }
+		m01, m12, m20 := mid(tri[0], tri[1]), mid(tri[1], tri[2]), mid(tri[2], tri[0])
+		for _, sub := range [][3]r3.Vec{
+			{tri[0], m01, m20},
+			{m01, tri[1], m12},
+			{m20, m12, tri[2]},
+			{m01, m12, m20},
+		} {
+			fisheyeTriangle(img, sub, proj, col, depth+1)
+		}
+		return
+	}
+
+	var v [3]rasterVert
+	for i, p := range tri {
+		dist := r3.Norm(p)
+		if dist == 0 {
+			return
+		}
+		alt := math.Asin(p.Z/dist) * 180 / math.Pi
+		az := math.Atan2(p.X, p.Y) * 180 / math.Pi
+		v[i].X, v[i].Y = proj(alt, az)
+		v[i].Z, v[i].InvW = dist, 1
+	}
+	img.fillTriangle(v, func([3]float64) color.RGBA { return col })
+}
+
+// lerpRGBA linearly interpolates between colors a and b.
+func lerpRGBA(a, b color.RGBA, f float64) color.RGBA {
+	l := func(x, y uint8) uint8 { return uint8(float64(x) + f*(float64(y)-float64(x))) }
+	return color.RGBA{l(a.R, b.R), l(a.G, b.G), l(a.B, b.B), l(a.A, b.A)}
+}
@@ -0,0 +1,134 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// A zImage is an RGBA image with a depth buffer for rasterizing
+// triangles.
+type zImage struct {
+	*image.RGBA
+	depth []float64
+}
+
+func newZImage(w, h int) *zImage {
+	img := &zImage{image.NewRGBA(image.Rect(0, 0, w, h)), make([]float64, w*h)}
+	for i := range img.depth {
+		img.depth[i] = math.Inf(1)
+	}
+	return img
+}
+
+// A rasterVert is a triangle vertex in screen space.
+type rasterVert struct {
+	X, Y float64 // Pixel coordinates
+
+	// Z is the depth of this vertex. Smaller values are nearer.
+	Z float64
+
+	// InvW is 1/w of this vertex in clip space, for perspective-correct
+	// interpolation. If the projection is not a perspective
+	// projection, this should be 1.
+	InvW float64
+}
+
+// fillTriangle rasterizes the triangle with vertexes v into img. For
+// each pixel that passes the depth test, it calls frag with the
+// barycentric coordinates of that pixel within the triangle, which
+// returns the pixel's color.
+func (img *zImage) fillTriangle(v [3]rasterVert, frag func(b [3]float64) color.RGBA) {
+	// Compute the bounding box, clipped to the image.
+	bounds := img.Bounds()
+	minX := math.Max(math.Floor(math.Min(v[0].X, math.Min(v[1].X, v[2].X))), float64(bounds.Min.X))
+	maxX := math.Min(math.Ceil(math.Max(v[0].X, math.Max(v[1].X, v[2].X))), float64(bounds.Max.X-1))
+	minY := math.Max(math.Floor(math.Min(v[0].Y, math.Min(v[1].Y, v[2].Y))), float64(bounds.Min.Y))
+	maxY := math.Min(math.Ceil(math.Max(v[0].Y, math.Max(v[1].Y, v[2].Y))), float64(bounds.Max.Y-1))
+
+	edge := func(a, b rasterVert, x, y float64) float64 {
+		return (b.X-a.X)*(y-a.Y) - (b.Y-a.Y)*(x-a.X)
+	}
+	area := edge(v[0], v[1], v[2].X, v[2].Y)
+	if area == 0 {
+		return
+	}
+	for y := minY; y <= maxY; y++ {
+		for x := minX; x <= maxX; x++ {
+			px, py := x+0.5, y+0.5
+			// Screen-space barycentric coordinates. These all have
+			// the same sign as area if the pixel is inside the
+			// triangle, regardless of winding order.
+			l := [3]float64{
+				edge(v[1], v[2], px, py) / area,
+				edge(v[2], v[0], px, py) / area,
+				edge(v[0], v[1], px, py) / area,
+			}
+			if l[0] < 0 || l[1] < 0 || l[2] < 0 {
+				continue
+			}
+			z := l[0]*v[0].Z + l[1]*v[1].Z + l[2]*v[2].Z
+			i := int(y)*bounds.Dx() + int(x)
+			if z >= img.depth[i] {
+				continue
+			}
+			img.depth[i] = z
+			// Perspective-correct barycentric coordinates.
+			b := [3]float64{l[0] * v[0].InvW, l[1] * v[1].InvW, l[2] * v[2].InvW}
+			sum := b[0] + b[1] + b[2]
+			b[0], b[1], b[2] = b[0]/sum, b[1]/sum, b[2]/sum
+			img.SetRGBA(int(x), int(y), frag(b))
+		}
+	}
+}
+
+// drawLine draws a line from (x0, y0) to (x1, y1) on img, ignoring
+// depth. The line is width pixels wide.
+func drawLine(img *image.RGBA, x0, y0, x1, y1 float64, width float64, c color.RGBA) {
+	steps := int(math.Max(math.Abs(x1-x0), math.Abs(y1-y0))) + 1
+	r := width / 2
+	for i := 0; i <= steps; i++ {
+		f := float64(i) / float64(steps)
+		x, y := x0+f*(x1-x0), y0+f*(y1-y0)
+		for dy := -r; dy <= r; dy++ {
+			for dx := -r; dx <= r; dx++ {
+				img.SetRGBA(int(math.Round(x+dx)), int(math.Round(y+dy)), c)
+			}
+		}
+	}
+}
+
+// drawText draws txt on img centered at (x, y).
+func drawText(img *image.RGBA, x, y int, txt string, c color.Color) {
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(c),
+		Face: basicfont.Face7x13,
+	}
+	w := d.MeasureString(txt)
+	d.Dot = fixed.Point26_6{
+		X: fixed.I(x) - w/2,
+		Y: fixed.I(y) + fixed.I(basicfont.Face7x13.Ascent)/2,
+	}
+	d.DrawString(txt)
+}
+
+// SavePNG writes img to path as a PNG.
+func SavePNG(img image.Image, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	return f.Close()
+}
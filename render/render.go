@@ -1,25 +1,45 @@
-package main
+// Package render draws images and animations of shade models.
+package render
 
 import (
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"os/exec"
 	"text/template"
 	"time"
+
+	"github.com/aclements/shade"
+	"github.com/aclements/shade/solar"
 )
 
-func (m *ShadeModel) Render(testPos, cameraOffset [3]float64, t time.Time, outPath string) {
-	m.withPOV(testPos, outPath, func(src io.Writer) {
-		p := GetSunPos(t, m.lat, m.lon)
+// POV renders the model m from testPos + cameraOffset looking at
+// testPos, lit by the sun at time t, and writes an 800×600 PNG to
+// outPath. It uses POV-Ray if it is installed, and otherwise falls back
+// to Image. POV-Ray shows the render as it goes and waits for the user
+// to close it.
+func POV(m *shade.ShadeModel, testPos, cameraOffset [3]float64, t time.Time, outPath string) error {
+	return pov(m, testPos, cameraOffset, t, outPath, 800, 600, "+P") // Pause
+}
+
+// pov is like POV, but renders a width×height image and passes flags to
+// POV-Ray.
+func pov(m *shade.ShadeModel, testPos, cameraOffset [3]float64, t time.Time, outPath string, width, height int, flags ...string) error {
+	if _, err := exec.LookPath("povray"); err != nil {
+		img := Image(m, testPos, cameraOffset, t, width, height)
+		return SavePNG(img, outPath)
+	}
+	flags = append(flags, fmt.Sprintf("+W%d", width), fmt.Sprintf("+H%d", height))
+	return withPOV(m, testPos, outPath, flags, func(src io.Writer) error {
+		p := solar.GetSunPos(t, m.Latitude(), m.Longitude())
 		fmt.Fprintf(src, "setSun(%g, %g)\n", p.Altitude, p.Azimuth)
 		if err := testSceneTemplate.Execute(src, &cameraOffset); err != nil {
-			log.Fatalf("writing POV-Ray input: %s", err)
+			return err
 		}
-		for i := range m.layers {
+		for i := range m.ActiveLayers() {
 			fmt.Fprintf(src, "object {\n\tmesh%d\n\ttexture { pigment { color White } }\n}\n", i)
 		}
+		return nil
 	})
 }
 
@@ -84,7 +104,10 @@ text {
   }
 `))
 
-func (m *ShadeModel) withPOV(testPos [3]float64, output string, cb func(src io.Writer)) {
+// withPOV writes the POV-Ray scene of m with the test point at testPos,
+// followed by the output of cb, and runs POV-Ray with flags to render it
+// to output.
+func withPOV(m *shade.ShadeModel, testPos [3]float64, output string, flags []string, cb func(src io.Writer) error) error {
 	// The POV-Ray coordinate system looks like:
 	//
 	//	Y
@@ -97,28 +120,31 @@ func (m *ShadeModel) withPOV(testPos [3]float64, output string, cb func(src io.W
 	// As of Pov-Ray 3.7, it only supports input from stdin on DOS (?!)
 	src, err := os.CreateTemp("", "shade-*.pov")
 	if err != nil {
-		log.Fatalf("creating temporary file: %s", err)
+		return fmt.Errorf("creating POV-Ray input: %w", err)
 	}
 	defer os.Remove(src.Name())
+	defer src.Close()
 
 	var tmplArgs struct {
 		Lat, Lon float64
 		TestPos  [3]float64
 	}
-	tmplArgs.Lat, tmplArgs.Lon = m.lat, m.lon
+	tmplArgs.Lat, tmplArgs.Lon = m.Latitude(), m.Longitude()
 	tmplArgs.TestPos = testPos
 	if err := povTemplate.Execute(src, &tmplArgs); err != nil {
-		log.Fatalf("writing POV-Ray input: %s", err)
+		return fmt.Errorf("writing POV-Ray input: %w", err)
 	}
-	for i, l := range m.layers {
+	for i, l := range m.ActiveLayers() {
 		fmt.Fprintf(src, "#declare mesh%d = ", i)
-		if err := l.mesh.ToPOV(src); err != nil {
-			log.Fatalf("writing POV-Ray input: %s", err)
+		if err := l.Mesh.ToPOV(src); err != nil {
+			return fmt.Errorf("writing POV-Ray input for layer %s: %w", l.Name, err)
 		}
 	}
-	cb(src)
+	if err := cb(src); err != nil {
+		return fmt.Errorf("writing POV-Ray input: %w", err)
+	}
 	if err := src.Close(); err != nil {
-		log.Fatalf("writing POV-Ray input: %s", err)
+		return fmt.Errorf("writing POV-Ray input: %w", err)
 	}
 
 	// Run povray
@@ -128,15 +154,15 @@ func (m *ShadeModel) withPOV(testPos [3]float64, output string, cb func(src io.W
 	}
 	args = append(args,
 		"+O"+output, // Output file
-		"+P",        // Pause
 		"+A",        // Anti-alias
-		//"-D",         // Disable display preview
 	)
+	args = append(args, flags...)
 	pov := exec.Command("povray", args...)
 	pov.Stdout, pov.Stderr = os.Stdout, os.Stderr
 	if err := pov.Run(); err != nil {
-		log.Fatalf("running POV-Ray: %s", err)
+		return fmt.Errorf("running POV-Ray: %w", err)
 	}
+	return nil
 }
 
 var povTemplate = template.Must(template.New("pov").Parse(`
@@ -0,0 +1,385 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"time"
+
+	"github.com/aclements/shade"
+	"github.com/aclements/shade/geom"
+	"github.com/aclements/shade/solar"
+	"gonum.org/v1/gonum/spatial/r3"
+)
+
+// Image renders the model m as seen from testPos + cameraOffset
+// looking at testPos, lit by the sun at time t. The image shows the same
+// scene as POV, including the test point marker and axes, but is
+// rasterized in-process with a shadow map instead of using POV-Ray.
+func Image(m *shade.ShadeModel, testPos, cameraOffset [3]float64, t time.Time, width, height int) *image.RGBA {
+	// Render at twice the resolution and downsample to anti-alias.
+	const ss = 2
+	target := r3.Vec{X: testPos[0], Y: testPos[1], Z: testPos[2]}
+	cam := newCamera(r3.Add(target, r3.Vec{X: cameraOffset[0], Y: cameraOffset[1], Z: cameraOffset[2]}), target, width*ss, height*ss)
+
+	// Collect the scene. These colors match POV, which match
+	// SketchUp.
+	type object struct {
+		mesh *geom.Mesh
+		col  color.RGBA
+	}
+	var scene []object
+	for _, l := range m.ActiveLayers() {
+		scene = append(scene, object{l.Mesh, color.RGBA{0xff, 0xff, 0xff, 0xff}})
+	}
+	green, red, blue := color.RGBA{0, 0xff, 0, 0xff}, color.RGBA{0xff, 0, 0, 0xff}, color.RGBA{0, 0, 0xff, 0xff}
+	const axis = 3 * 12
+	scene = append(scene,
+		object{sphereMesh(target, 6, 16), green},
+		object{cylinderMesh(target, r3.Add(target, r3.Vec{X: axis}), 3, 12), red},
+		object{cylinderMesh(target, r3.Add(target, r3.Vec{Y: axis}), 3, 12), green},
+		object{cylinderMesh(target, r3.Add(target, r3.Vec{Z: axis}), 3, 12), blue},
+	)
+
+	sun := solar.GetSunPos(t, m.Latitude(), m.Longitude())
+	var shadow *shadowMap
+	if sun.Altitude > 0 {
+		var meshes []*geom.Mesh
+		for _, o := range scene {
+			meshes = append(meshes, o.mesh)
+		}
+		shadow = newShadowMap(sun.Ray([3]float64{}).Dir, meshes, 2048)
+	}
+
+	img := newZImage(width*ss, height*ss)
+	cam.drawSky(img.RGBA)
+	for _, o := range scene {
+		for _, idxs := range o.mesh.Tris {
+			var tri [3]r3.Vec
+			for i, idx := range idxs {
+				v := o.mesh.Verts[idx]
+				tri[i] = r3.Vec{X: v[0], Y: v[1], Z: v[2]}
+			}
+			n := r3.Cross(r3.Sub(tri[1], tri[0]), r3.Sub(tri[2], tri[0]))
+			if r3.Norm(n) == 0 {
+				continue
+			}
+			n = r3.Unit(n)
+			// Meshes aren't consistently wound, so light both sides
+			// by facing the normal toward the camera.
+			if r3.Dot(n, r3.Sub(cam.eye, tri[0])) < 0 {
+				n = r3.Scale(-1, n)
+			}
+			const ambient, diffuse = 0.35, 0.75
+			var lambert float64
+			if shadow != nil {
+				lambert = math.Max(0, r3.Dot(n, shadow.dir))
+			}
+			cam.fillTriangle(img, tri, func(p r3.Vec) color.RGBA {
+				f := ambient
+				if lambert > 0 {
+					f += diffuse * lambert * shadow.lit(p, lambert)
+				}
+				return scaleRGBA(o.col, f)
+			})
+		}
+	}
+
+	out := downsample(img.RGBA, ss)
+	// Label north. Like POV, this sits just past the end of the north
+	// axis.
+	north := r3.Add(target, r3.Vec{Y: axis + 12})
+	if x, y, z := cam.project(north); z > cam.near {
+		drawText(out, int(x/ss), int(y/ss), "N", green)
+	}
+	return out
+}
+
+// A camera is a perspective projection.
+type camera struct {
+	eye                   r3.Vec
+	right, up, forward    r3.Vec
+	width, height         float64
+	halfWidth, halfHeight float64 // Tangent of half the field of view
+	near                  float64
+}
+
+// newCamera returns a camera at eye looking at target, with Z up. The
+// field of view matches POV-Ray's default camera, which is about 67°
+// horizontally.
+func newCamera(eye, target r3.Vec, width, height int) *camera {
+	c := &camera{eye: eye, width: float64(width), height: float64(height), near: 1}
+	c.forward = r3.Unit(r3.Sub(target, eye))
+	up := r3.Vec{Z: 1}
+	if math.Abs(r3.Dot(c.forward, up)) > 0.999 {
+		// Looking straight up or down.
+		up = r3.Vec{Y: 1}
+	}
+	c.right = r3.Unit(r3.Cross(c.forward, up))
+	c.up = r3.Cross(c.right, c.forward)
+	c.halfWidth = 1.33 / 2
+	c.halfHeight = c.halfWidth * c.height / c.width
+	return c
+}
+
+// view returns p in camera space, where Z is the distance in front of
+// the camera.
+func (c *camera) view(p r3.Vec) r3.Vec {
+	d := r3.Sub(p, c.eye)
+	return r3.Vec{X: r3.Dot(d, c.right), Y: r3.Dot(d, c.up), Z: r3.Dot(d, c.forward)}
+}
+
+// screen projects camera-space point v to pixel coordinates.
+func (c *camera) screen(v r3.Vec) (x, y float64) {
+	x = c.width / 2 * (1 + v.X/v.Z/c.halfWidth)
+	y = c.height / 2 * (1 - v.Y/v.Z/c.halfHeight)
+	return
+}
+
+// project returns the pixel coordinates and depth of world point p.
+func (c *camera) project(p r3.Vec) (x, y, z float64) {
+	v := c.view(p)
+	x, y = c.screen(v)
+	return x, y, v.Z
+}
+
+// fillTriangle rasterizes world-space triangle tri into img, clipping it
+// against the near plane. frag returns the color of the surface at a
+// world-space point.
+func (c *camera) fillTriangle(img *zImage, tri [3]r3.Vec, frag func(p r3.Vec) color.RGBA) {
+	type vert struct{ world, view r3.Vec }
+	var poly []vert
+	for _, p := range tri {
+		poly = append(poly, vert{p, c.view(p)})
+	}
+
+	// Clip against the near plane (Sutherland–Hodgman).
+	var clipped []vert
+	for i, a := range poly {
+		b := poly[(i+1)%len(poly)]
+		if a.view.Z >= c.near {
+			clipped = append(clipped, a)
+		}
+		if (a.view.Z >= c.near) != (b.view.Z >= c.near) {
+			f := (c.near - a.view.Z) / (b.view.Z - a.view.Z)
+			clipped = append(clipped, vert{
+				r3.Add(a.world, r3.Scale(f, r3.Sub(b.world, a.world))),
+				r3.Add(a.view, r3.Scale(f, r3.Sub(b.view, a.view))),
+			})
+		}
+	}
+
+	// Draw the clipped polygon as a triangle fan.
+	for i := 2; i < len(clipped); i++ {
+		vs := [3]vert{clipped[0], clipped[i-1], clipped[i]}
+		var rv [3]rasterVert
+		for j, v := range vs {
+			rv[j].X, rv[j].Y = c.screen(v.view)
+			// 1/z interpolates linearly in screen space, so use it
+			// for the depth test. Negate it so nearer is smaller.
+			rv[j].InvW = 1 / v.view.Z
+			rv[j].Z = -rv[j].InvW
+		}
+		img.fillTriangle(rv, func(b [3]float64) color.RGBA {
+			p := r3.Add(r3.Add(r3.Scale(b[0], vs[0].world), r3.Scale(b[1], vs[1].world)), r3.Scale(b[2], vs[2].world))
+			return frag(p)
+		})
+	}
+}
+
+// drawSky fills img with a sky gradient like POV's sky sphere.
+func (c *camera) drawSky(img *image.RGBA) {
+	horizon := color.RGBA{0xff, 0xff, 0xff, 0xff}
+	low := color.RGBA{0x25, 0x3a, 0x99, 0xff}
+	high := color.RGBA{0x13, 0x24, 0x60, 0xff}
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			sx := (2*(float64(x)+0.5)/c.width - 1) * c.halfWidth
+			sy := (1 - 2*(float64(y)+0.5)/c.height) * c.halfHeight
+			dir := r3.Unit(r3.Add(c.forward, r3.Add(r3.Scale(sx, c.right), r3.Scale(sy, c.up))))
+			var col color.RGBA
+			switch {
+			case dir.Z <= 0:
+				col = horizon
+			case dir.Z < 0.3:
+				col = lerpRGBA(horizon, low, dir.Z/0.3)
+			default:
+				col = lerpRGBA(low, high, (dir.Z-0.3)/0.7)
+			}
+			img.SetRGBA(x, y, col)
+		}
+	}
+}
+
+// A shadowMap is a depth map of the scene rendered orthographically
+// from the sun.
+type shadowMap struct {
+	dir    r3.Vec // Direction toward the sun
+	u, v   r3.Vec // Axes of the map
+	min    [2]float64
+	scale  float64 // Pixels per model unit
+	bias   float64
+	size   int
+	depths []float64
+}
+
+func newShadowMap(dir r3.Vec, meshes []*geom.Mesh, size int) *shadowMap {
+	s := &shadowMap{dir: dir, size: size}
+	ref := r3.Vec{Z: 1}
+	if math.Abs(dir.Z) > 0.999 {
+		ref = r3.Vec{Y: 1}
+	}
+	s.u = r3.Unit(r3.Cross(ref, dir))
+	s.v = r3.Cross(dir, s.u)
+
+	// Fit the map to the scene.
+	s.min = [2]float64{math.Inf(1), math.Inf(1)}
+	max := [2]float64{math.Inf(-1), math.Inf(-1)}
+	for _, mesh := range meshes {
+		for _, p := range mesh.Verts {
+			v := r3.Vec{X: p[0], Y: p[1], Z: p[2]}
+			u, w := r3.Dot(v, s.u), r3.Dot(v, s.v)
+			s.min[0], s.min[1] = math.Min(s.min[0], u), math.Min(s.min[1], w)
+			max[0], max[1] = math.Max(max[0], u), math.Max(max[1], w)
+		}
+	}
+	extent := math.Max(max[0]-s.min[0], max[1]-s.min[1])
+	if extent == 0 {
+		extent = 1
+	}
+	s.scale = float64(size-1) / extent
+	// Allow a few pixels of slop to avoid surfaces shadowing
+	// themselves.
+	s.bias = 3 / s.scale
+
+	img := newZImage(size, size)
+	for _, mesh := range meshes {
+		for _, idxs := range mesh.Tris {
+			var rv [3]rasterVert
+			for i, idx := range idxs {
+				p := mesh.Verts[idx]
+				rv[i].X, rv[i].Y, rv[i].Z = s.project(r3.Vec{X: p[0], Y: p[1], Z: p[2]})
+				rv[i].InvW = 1
+			}
+			img.fillTriangle(rv, func([3]float64) color.RGBA { return color.RGBA{} })
+		}
+	}
+	s.depths = img.depth
+	return s
+}
+
+// project returns the pixel coordinates and depth of p in the map.
+// Points nearer the sun have smaller depths.
+func (s *shadowMap) project(p r3.Vec) (x, y, z float64) {
+	return (r3.Dot(p, s.u) - s.min[0]) * s.scale, (r3.Dot(p, s.v) - s.min[1]) * s.scale, -r3.Dot(p, s.dir)
+}
+
+// lit returns the fraction of the area around p that is lit by the sun,
+// from 0 to 1. It averages neighboring pixels to soften shadow edges.
+// cos is the cosine of the angle between the surface at p and the sun.
+func (s *shadowMap) lit(p r3.Vec, cos float64) float64 {
+	x, y, z := s.project(p)
+	// Surfaces at a glancing angle to the sun span a larger range of
+	// depths in each pixel, so they need more bias.
+	bias := s.bias * math.Min(1+math.Sqrt(1-cos*cos)/cos, 10)
+	lit, n := 0, 0
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			px, py := int(x)+dx, int(y)+dy
+			if px < 0 || py < 0 || px >= s.size || py >= s.size {
+				continue
+			}
+			n++
+			if z <= s.depths[py*s.size+px]+bias {
+				lit++
+			}
+		}
+	}
+	if n == 0 {
+		return 1
+	}
+	return float64(lit) / float64(n)
+}
+
+// sphereMesh returns a UV sphere mesh.
+func sphereMesh(center r3.Vec, radius float64, n int) *geom.Mesh {
+	m := new(geom.Mesh)
+	for i := 0; i <= n; i++ {
+		theta := math.Pi * float64(i) / float64(n)
+		for j := 0; j < 2*n; j++ {
+			phi := math.Pi * float64(j) / float64(n)
+			m.Verts = append(m.Verts, [3]float64{
+				center.X + radius*math.Sin(theta)*math.Cos(phi),
+				center.Y + radius*math.Sin(theta)*math.Sin(phi),
+				center.Z + radius*math.Cos(theta),
+			})
+		}
+	}
+	for i := 0; i < n; i++ {
+		for j := 0; j < 2*n; j++ {
+			a, b := i*2*n+j, i*2*n+(j+1)%(2*n)
+			m.Tris = append(m.Tris, [3]int{a, b, a + 2*n}, [3]int{b, b + 2*n, a + 2*n})
+		}
+	}
+	return m
+}
+
+// cylinderMesh returns a capped cylinder mesh from a to b.
+func cylinderMesh(a, b r3.Vec, radius float64, n int) *geom.Mesh {
+	axis := r3.Unit(r3.Sub(b, a))
+	ref := r3.Vec{Z: 1}
+	if math.Abs(axis.Z) > 0.999 {
+		ref = r3.Vec{X: 1}
+	}
+	u := r3.Unit(r3.Cross(axis, ref))
+	v := r3.Cross(axis, u)
+	m := new(geom.Mesh)
+	for _, end := range []r3.Vec{a, b} {
+		m.Verts = append(m.Verts, [3]float64{end.X, end.Y, end.Z})
+		for i := 0; i < n; i++ {
+			phi := 2 * math.Pi * float64(i) / float64(n)
+			p := r3.Add(end, r3.Add(r3.Scale(radius*math.Cos(phi), u), r3.Scale(radius*math.Sin(phi), v)))
+			m.Verts = append(m.Verts, [3]float64{p.X, p.Y, p.Z})
+		}
+	}
+	// Vertex 0 and n+1 are the centers of the caps.
+	for i := 0; i < n; i++ {
+		a0, a1 := 1+i, 1+(i+1)%n
+		b0, b1 := a0+n+1, a1+n+1
+		m.Tris = append(m.Tris,
+			[3]int{0, a1, a0},
+			[3]int{n + 1, b0, b1},
+			[3]int{a0, a1, b1},
+			[3]int{a0, b1, b0},
+		)
+	}
+	return m
+}
+
+// scaleRGBA scales the brightness of c by f, clamping to white.
+func scaleRGBA(c color.RGBA, f float64) color.RGBA {
+	s := func(x uint8) uint8 { return uint8(math.Min(255, float64(x)*f)) }
+	return color.RGBA{s(c.R), s(c.G), s(c.B), c.A}
+}
+
+// downsample shrinks img by an integer factor by averaging each block of
+// pixels.
+func downsample(img *image.RGBA, factor int) *image.RGBA {
+	b := img.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, b.Dx()/factor, b.Dy()/factor))
+	n := uint32(factor * factor)
+	for y := 0; y < out.Rect.Dy(); y++ {
+		for x := 0; x < out.Rect.Dx(); x++ {
+			var r, g, bl, a uint32
+			for dy := 0; dy < factor; dy++ {
+				for dx := 0; dx < factor; dx++ {
+					c := img.RGBAAt(b.Min.X+x*factor+dx, b.Min.Y+y*factor+dy)
+					r, g, bl, a = r+uint32(c.R), g+uint32(c.G), bl+uint32(c.B), a+uint32(c.A)
+				}
+			}
+			out.SetRGBA(x, y, color.RGBA{uint8(r / n), uint8(g / n), uint8(bl / n), uint8(a / n)})
+		}
+	}
+	return out
+}
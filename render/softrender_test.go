@@ -0,0 +1,62 @@
+package render
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aclements/shade"
+	"github.com/aclements/shade/geom"
+	"github.com/aclements/shade/solar"
+	"gonum.org/v1/gonum/spatial/r3"
+)
+
+func TestShadowMap(t *testing.T) {
+	ground := &geom.Mesh{
+		Verts: [][3]float64{{-500, -500, 0}, {500, -500, 0}, {500, 500, 0}, {-500, 500, 0}},
+		Tris:  [][3]int{{0, 1, 2}, {0, 2, 3}},
+	}
+	// A disk floating over the origin.
+	disk := cylinderMesh(r3.Vec{Z: 100}, r3.Vec{Z: 110}, 50, 16)
+
+	// Sun from the south at 45°, so the disk's shadow falls 100 units
+	// north of it.
+	dir := solar.SunPos{Altitude: 45, Azimuth: 180}.Ray([3]float64{}).Dir
+	s := newShadowMap(dir, []*geom.Mesh{ground, disk}, 512)
+	cos := dir.Z // Ground normal is +Z
+
+	for _, test := range []struct {
+		p    r3.Vec
+		want float64
+	}{
+		{r3.Vec{Y: 100}, 0},
+		{r3.Vec{}, 1},
+		{r3.Vec{X: 200, Y: 100}, 1},
+		{r3.Vec{Z: 110}, 1}, // Top of the disk
+	} {
+		if got := s.lit(test.p, cos); got != test.want {
+			t.Errorf("lit(%v) = %v, want %v", test.p, got, test.want)
+		}
+	}
+}
+
+func TestPOVFrameSize(t *testing.T) {
+	// Without POV-Ray, POV frames fall back to Image, which must still
+	// honor the animation's frame size.
+	t.Setenv("PATH", "")
+	m := shade.NewShadeModel(42.4195011, -71.2064993, 200)
+	m.AddMesh("disk", cylinderMesh(r3.Vec{Z: 100}, r3.Vec{Z: 110}, 50, 16), false)
+	a := &Animation{
+		CameraOffset: [3]float64{400, -300, 100},
+		Times:        []time.Time{time.Date(2022, 6, 21, 12, 0, 0, 0, time.UTC)},
+		Width:        64,
+		Height:       48,
+		POVRay:       true,
+	}
+	frames, err := Frames(m, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := frames[0].Bounds().Size(); got.X != a.Width || got.Y != a.Height {
+		t.Errorf("want %dx%d frame, got %dx%d", a.Width, a.Height, got.X, got.Y)
+	}
+}
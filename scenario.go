@@ -0,0 +1,126 @@
+package shade
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// A Scenario is a "what if" variation on the layers of a ShadeModel,
+// such as removing a tree or replacing a building with a taller one.
+// Layers are identified by name (see ShadeModel.LayerNames).
+type Scenario struct {
+	Name string
+
+	// Enable and Disable list layers to enable or disable in this
+	// scenario. Other layers retain their state from the base model.
+	Enable, Disable []string
+
+	// Substitute maps from layer names to the names of layers to use in
+	// their place. The substituted layer is disabled and its
+	// replacement is enabled. Typically the replacement is a layer that
+	// is disabled in the base model. Substitutions can't be chained: a
+	// replacement can't itself be substituted.
+	Substitute map[string]string
+}
+
+// WithScenario returns a copy of m with the layer changes of s applied.
+// m itself is not modified. The copy shares meshes with m.
+func (m *ShadeModel) WithScenario(s *Scenario) (*ShadeModel, error) {
+	m2 := m.Clone()
+
+	set := func(name string, enabled bool) error {
+		if err := m2.SetLayerEnabled(name, enabled); err != nil {
+			return fmt.Errorf("scenario %q: %w", s.Name, err)
+		}
+		return nil
+	}
+	for _, name := range s.Enable {
+		if err := set(name, true); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range s.Disable {
+		if err := set(name, false); err != nil {
+			return nil, err
+		}
+	}
+	// Apply substitutions in a fixed order, so errors are
+	// deterministic.
+	var froms []string
+	for from := range s.Substitute {
+		froms = append(froms, from)
+	}
+	sort.Strings(froms)
+	for _, from := range froms {
+		to := s.Substitute[from]
+		if _, ok := s.Substitute[to]; ok {
+			return nil, fmt.Errorf("scenario %q: layer %s replaces %s but is also replaced", s.Name, to, from)
+		}
+		if err := set(from, false); err != nil {
+			return nil, err
+		}
+		if err := set(to, true); err != nil {
+			return nil, err
+		}
+	}
+	return m2, nil
+}
+
+// A ScenarioResult is the analysis of a single Scenario.
+type ScenarioResult struct {
+	Scenario  *Scenario
+	Intensity *IntensityOverTime
+}
+
+// CompareScenarios computes the sun exposure at testPos over year in
+// loc for each scenario. A nil Scenario represents the model as-is. The results
+// are in the same order as scenarios.
+func (m *ShadeModel) CompareScenarios(ctx context.Context, year int, loc *time.Location, testPos [3]float64, scenarios []*Scenario) ([]*ScenarioResult, error) {
+	var results []*ScenarioResult
+	for _, s := range scenarios {
+		sm := m
+		if s != nil {
+			var err error
+			sm, err = m.WithScenario(s)
+			if err != nil {
+				return nil, err
+			}
+		}
+		o, err := sm.IntensityOverYear(ctx, year, loc, testPos)
+		if err != nil {
+			return nil, fmt.Errorf("scenario %q: %w", s.name(), err)
+		}
+		results = append(results, &ScenarioResult{s, o})
+	}
+	return results, nil
+}
+
+// name returns the name of s for display. s may be nil.
+func (s *Scenario) name() string {
+	if s == nil || s.Name == "" {
+		return "(base)"
+	}
+	return s.Name
+}
+
+// WriteScenarioSummary writes a table summarizing the sun exposure of
+// each scenario to w. Changes are relative to the first result.
+func WriteScenarioSummary(w io.Writer, results []*ScenarioResult) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "Scenario\tDirect sun (h)\tFoliage sun (h)\tInsolation (kWh/m²)\tΔ direct (h)\tΔ insolation (kWh/m²)\t\n")
+	var base SunTotals
+	for i, r := range results {
+		t := r.Intensity.Totals()
+		if i == 0 {
+			base = t
+		}
+		fmt.Fprintf(tw, "%s\t%.1f\t%.1f\t%.1f\t%+.1f\t%+.1f\t\n", r.Scenario.name(),
+			t.Direct.Hours(), t.Foliage.Hours(), t.Insolation,
+			(t.Direct - base.Direct).Hours(), t.Insolation-base.Insolation)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,29 @@
+package shade
+
+import "testing"
+
+func TestScenarioSubstitute(t *testing.T) {
+	m := NewShadeModel(42.4195011, -71.2064993, 200)
+	for _, name := range []string{"a", "b", "c"} {
+		m.AddMesh(name, southWall(), false)
+	}
+	if err := m.SetLayerEnabled("b", false); err != nil {
+		t.Fatal(err)
+	}
+
+	m2, err := m.WithScenario(&Scenario{Name: "swap", Substitute: map[string]string{"a": "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m2.Layer("a").Disabled || m2.Layer("b").Disabled || m2.Layer("c").Disabled {
+		t.Errorf("want a disabled and b and c enabled")
+	}
+	if m.Layer("a").Disabled || !m.Layer("b").Disabled {
+		t.Errorf("WithScenario modified the base model")
+	}
+
+	// Chained substitutions are ambiguous.
+	if _, err := m.WithScenario(&Scenario{Name: "chain", Substitute: map[string]string{"a": "b", "b": "c"}}); err == nil {
+		t.Errorf("want error for chained substitutions")
+	}
+}
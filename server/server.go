@@ -0,0 +1,658 @@
+// Package server serves shade analyses over HTTP.
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aclements/shade"
+	"github.com/aclements/shade/chart"
+	"github.com/aclements/shade/geom"
+)
+
+// A Server serves a REST API for running shade analyses from other
+// tools, such as dashboards and notebooks. Requests and responses are
+// JSON, except for mesh uploads and plots.
+//
+//	POST /sites                      Create a site: {"lat", "lon", "elevation", "timezone"}
+//	GET  /sites/{site}               Describe a site, its layers, and its test points
+//	GET  /sites/{site}/view          Open the 3D viewer (see getViewer)
+//	GET  /sites/{site}/meshes        Get the meshes of all layers
+//	GET  /sites/{site}/sun?time=t    Get the sun position at local time t, like
+//	                                 "2022-06-21T15:04", and with &pos=x,y,z, the
+//	                                 sun light at that position
+//	POST /sites/{site}/layers        Add a layer (see below)
+//	PUT  /sites/{site}/points/{name} Define a test point: [x, y, z]
+//	POST /sites/{site}/analyses      Start an analysis (see analysisRequest)
+//	GET  /jobs/{job}                 Get the status and progress of an analysis
+//	DELETE /jobs/{job}               Cancel a running analysis
+//	GET  /jobs/{job}/totals          Get the total and monthly sun exposure
+//	GET  /jobs/{job}/series          Get the sun light series in shade.SeriesColumns format
+//	GET  /jobs/{job}/{plot}.{ext}    Get a plot: heatmap, duration, hours, or dli, in
+//	                                 any format supported by chart.WritePlot
+//
+// A layer can be uploaded as a binary STL request body with the query
+// parameters name and, for foliage, foliage=true. Alternatively, a
+// layer can reference a mesh file on the server with a JSON body of
+// {"name", "path", "foliage"}, where path is relative to the server's
+// root directory.
+//
+// Analyses run in the background, so clients should poll the job until
+// its status is "done", "failed", or "cancelled". A job's ID is derived
+// from its inputs, so starting the same analysis again returns the
+// existing job. The sun light series are cached on disk by
+// IntensityOverPeriod, so repeating an analysis after the server
+// restarts is also fast, and restarting a cancelled analysis resumes
+// where it left off. For the same reason, the server only remembers the
+// most recent finished jobs.
+type Server struct {
+	// Root is the directory that mesh paths are relative to. Paths
+	// can't refer to files outside Root.
+	Root string
+
+	mu       sync.Mutex
+	sites    map[string]*site
+	nextSite int
+	jobs     map[string]*job
+}
+
+// maxUpload is the largest mesh the server accepts, in bytes.
+const maxUpload = 256 << 20
+
+// New returns a Server that reads mesh files from root.
+func New(root string) *Server {
+	return &Server{Root: root, sites: make(map[string]*site), jobs: make(map[string]*job)}
+}
+
+type siteConfig struct {
+	Lat       float64 `json:"lat"`
+	Lon       float64 `json:"lon"`
+	Elevation float64 `json:"elevation"` // Feet
+
+	// TimeZone is the IANA time zone of the site, such as
+	// "America/New_York". It defaults to the server's local time zone.
+	TimeZone string `json:"timezone,omitempty"`
+}
+
+type site struct {
+	id     string
+	config siteConfig
+	loc    *time.Location
+	model  *shade.ShadeModel
+	points map[string][3]float64
+}
+
+type siteInfo struct {
+	ID string `json:"id"`
+	siteConfig
+	Layers []layerInfo           `json:"layers"`
+	Points map[string][3]float64 `json:"points"`
+}
+
+type layerInfo struct {
+	Name      string `json:"name"`
+	Foliage   bool   `json:"foliage"`
+	Triangles int    `json:"triangles"`
+}
+
+// analysisRequest is the body of a request to start an analysis.
+type analysisRequest struct {
+	// Point is the name of a test point of the site. Alternatively,
+	// Pos is an explicit test position.
+	Point string      `json:"point,omitempty"`
+	Pos   *[3]float64 `json:"pos,omitempty"`
+
+	// Year analyzes a calendar year. Alternatively, Start and End are
+	// dates like "2022-04-01" that bound the analysis. End is
+	// exclusive.
+	Year  int    `json:"year,omitempty"`
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+
+	// Increment is the time step, such as "10m". It defaults to 10
+	// minutes.
+	Increment string `json:"increment,omitempty"`
+}
+
+type job struct {
+	ID       string       `json:"id"`
+	Site     string       `json:"site"`
+	Status   string       `json:"status"` // "running", "done", "failed", or "cancelled"
+	Error    string       `json:"error,omitempty"`
+	Started  time.Time    `json:"started"`
+	Finished *time.Time   `json:"finished,omitempty"`
+	Progress *jobProgress `json:"progress,omitempty"`
+
+	cancel context.CancelFunc
+	result *shade.IntensityOverTime
+}
+
+// jobProgress is a shade.Progress in the API. It's immutable, so copies
+// of a job can share it.
+type jobProgress struct {
+	Traced int     `json:"traced"`
+	Total  int     `json:"total"`
+	ETA    float64 `json:"eta,omitempty"` // Seconds
+}
+
+// apiError is an error with an HTTP status code.
+type apiError struct {
+	code int
+	msg  string
+}
+
+func (e *apiError) Error() string { return e.msg }
+
+func errorf(code int, format string, args ...any) error {
+	return &apiError{code, fmt.Sprintf(format, args...)}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	err := s.route(w, r)
+	if err == nil {
+		return
+	}
+	code := http.StatusInternalServerError
+	var ae *apiError
+	if errors.As(err, &ae) {
+		code = ae.code
+	} else {
+		log.Printf("%s %s: %s", r.Method, r.URL.Path, err)
+	}
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+func (s *Server) route(w http.ResponseWriter, r *http.Request) error {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	method := func(m string) error {
+		if r.Method != m {
+			return errorf(http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+		}
+		return nil
+	}
+	switch {
+	case len(parts) == 1 && parts[0] == "sites":
+		if err := method(http.MethodPost); err != nil {
+			return err
+		}
+		return s.createSite(w, r)
+
+	case len(parts) >= 2 && parts[0] == "sites":
+		st, err := s.site(parts[1])
+		if err != nil {
+			return err
+		}
+		switch {
+		case len(parts) == 2:
+			if err := method(http.MethodGet); err != nil {
+				return err
+			}
+			return s.getSite(w, st)
+		case len(parts) == 3 && parts[2] == "layers":
+			if err := method(http.MethodPost); err != nil {
+				return err
+			}
+			return s.addLayer(w, r, st)
+		case len(parts) == 4 && parts[2] == "points":
+			if err := method(http.MethodPut); err != nil {
+				return err
+			}
+			return s.setPoint(w, r, st, parts[3])
+		case len(parts) == 3 && parts[2] == "analyses":
+			if err := method(http.MethodPost); err != nil {
+				return err
+			}
+			return s.startAnalysis(w, r, st)
+		case len(parts) == 3 && parts[2] == "view":
+			if err := method(http.MethodGet); err != nil {
+				return err
+			}
+			return s.getViewer(w)
+		case len(parts) == 3 && parts[2] == "meshes":
+			if err := method(http.MethodGet); err != nil {
+				return err
+			}
+			return s.getMeshes(w, st)
+		case len(parts) == 3 && parts[2] == "sun":
+			if err := method(http.MethodGet); err != nil {
+				return err
+			}
+			return s.getSun(w, r, st)
+		}
+
+	case (len(parts) == 2 || len(parts) == 3) && parts[0] == "jobs":
+		if len(parts) == 2 && r.Method == http.MethodDelete {
+			return s.cancelJob(w, parts[1])
+		}
+		if err := method(http.MethodGet); err != nil {
+			return err
+		}
+		s.mu.Lock()
+		j, ok := s.jobs[parts[1]]
+		var jc job
+		if ok {
+			jc = *j
+		}
+		s.mu.Unlock()
+		if !ok {
+			return errorf(http.StatusNotFound, "unknown job %q", parts[1])
+		}
+		if len(parts) == 2 {
+			return writeJSON(w, http.StatusOK, &jc)
+		}
+		if jc.Status != "done" {
+			return errorf(http.StatusConflict, "job %s is %s", jc.ID, jc.Status)
+		}
+		return s.getResult(w, jc.result, parts[2])
+	}
+	return errorf(http.StatusNotFound, "not found")
+}
+
+func (s *Server) site(id string) (*site, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.sites[id]
+	if !ok {
+		return nil, errorf(http.StatusNotFound, "unknown site %q", id)
+	}
+	return st, nil
+}
+
+func (s *Server) createSite(w http.ResponseWriter, r *http.Request) error {
+	var cfg siteConfig
+	if err := readJSON(r, &cfg); err != nil {
+		return err
+	}
+	if cfg.Lat < -90 || cfg.Lat > 90 || cfg.Lon < -180 || cfg.Lon > 180 {
+		return errorf(http.StatusBadRequest, "latitude or longitude out of range")
+	}
+	loc := time.Local
+	if cfg.TimeZone != "" {
+		var err error
+		if loc, err = time.LoadLocation(cfg.TimeZone); err != nil {
+			return errorf(http.StatusBadRequest, "%s", err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.addSite(shade.NewShadeModel(cfg.Lat, cfg.Lon, cfg.Elevation), loc)
+	return writeJSON(w, http.StatusCreated, st.info())
+}
+
+// AddSite adds m as a new site whose times are in loc and returns its
+// ID. This makes an already loaded model available through the API.
+func (s *Server) AddSite(m *shade.ShadeModel, loc *time.Location) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addSite(m, loc).id
+}
+
+// addSite adds a site for model m. The caller must hold s.mu.
+func (s *Server) addSite(m *shade.ShadeModel, loc *time.Location) *site {
+	s.nextSite++
+	st := &site{
+		id:     strconv.Itoa(s.nextSite),
+		config: siteConfig{m.Latitude(), m.Longitude(), m.ElevationFeet(), loc.String()},
+		loc:    loc,
+		model:  m,
+		points: make(map[string][3]float64),
+	}
+	s.sites[st.id] = st
+	return st
+}
+
+// info returns a description of st. The caller must hold s.mu.
+func (st *site) info() *siteInfo {
+	info := &siteInfo{ID: st.id, siteConfig: st.config, Layers: []layerInfo{}, Points: make(map[string][3]float64)}
+	for _, l := range st.model.Layers() {
+		info.Layers = append(info.Layers, layerInfo{l.Name, l.Foliage, len(l.Mesh.Tris)})
+	}
+	for name, p := range st.points {
+		info.Points[name] = p
+	}
+	return info
+}
+
+func (s *Server) getSite(w http.ResponseWriter, st *site) error {
+	s.mu.Lock()
+	info := st.info()
+	s.mu.Unlock()
+	return writeJSON(w, http.StatusOK, info)
+}
+
+func (s *Server) addLayer(w http.ResponseWriter, r *http.Request, st *site) error {
+	var req struct {
+		Name    string `json:"name"`
+		Path    string `json:"path"`
+		Foliage bool   `json:"foliage"`
+	}
+	var body io.Reader
+	if ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); ct == "application/json" {
+		// Reference a mesh on the server.
+		if err := readJSON(r, &req); err != nil {
+			return err
+		}
+		if req.Path == "" {
+			return errorf(http.StatusBadRequest, "missing mesh path")
+		}
+		p, err := s.meshPath(req.Path)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return errorf(http.StatusBadRequest, "%s", err)
+		}
+		defer f.Close()
+		body = f
+		if req.Name == "" {
+			req.Name = strings.TrimSuffix(path.Base(req.Path), path.Ext(req.Path))
+		}
+	} else {
+		// Upload a mesh.
+		q := r.URL.Query()
+		req.Name = q.Get("name")
+		req.Foliage, _ = strconv.ParseBool(q.Get("foliage"))
+		body = http.MaxBytesReader(w, r.Body, maxUpload)
+	}
+	if req.Name == "" {
+		return errorf(http.StatusBadRequest, "missing layer name")
+	}
+
+	// Parse the mesh before taking the lock so we don't hold it while
+	// reading the mesh.
+	mesh, err := geom.ReadSTL(body)
+	if err != nil {
+		return errorf(http.StatusBadRequest, "reading mesh: %s", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if st.model.Layer(req.Name) != nil {
+		return errorf(http.StatusConflict, "layer %q already exists", req.Name)
+	}
+	st.model.AddMesh(req.Name, &mesh.Mesh, req.Foliage)
+	return writeJSON(w, http.StatusCreated, st.info())
+}
+
+func (s *Server) setPoint(w http.ResponseWriter, r *http.Request, st *site, name string) error {
+	var pos [3]float64
+	if err := readJSON(r, &pos); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st.points[name] = pos
+	return writeJSON(w, http.StatusOK, st.info())
+}
+
+func (s *Server) startAnalysis(w http.ResponseWriter, r *http.Request, st *site) error {
+	var req analysisRequest
+	if err := readJSON(r, &req); err != nil {
+		return err
+	}
+
+	var start, end time.Time
+	switch {
+	case req.Year != 0:
+		start = time.Date(req.Year, 1, 1, 0, 0, 0, 0, st.loc)
+		end = start.AddDate(1, 0, 0)
+	case req.Start != "" && req.End != "":
+		var err error
+		if start, err = time.ParseInLocation("2006-01-02", req.Start, st.loc); err != nil {
+			return errorf(http.StatusBadRequest, "%s", err)
+		}
+		if end, err = time.ParseInLocation("2006-01-02", req.End, st.loc); err != nil {
+			return errorf(http.StatusBadRequest, "%s", err)
+		}
+	default:
+		return errorf(http.StatusBadRequest, "need year or start and end")
+	}
+	if !start.Before(end) {
+		return errorf(http.StatusBadRequest, "start must be before end")
+	}
+	increment := 10 * time.Minute
+	if req.Increment != "" {
+		var err error
+		if increment, err = time.ParseDuration(req.Increment); err != nil {
+			return errorf(http.StatusBadRequest, "%s", err)
+		}
+		if increment < time.Minute {
+			return errorf(http.StatusBadRequest, "increment must be at least 1m")
+		}
+	}
+
+	s.mu.Lock()
+	var pos [3]float64
+	switch {
+	case req.Pos != nil:
+		pos = *req.Pos
+	case req.Point != "":
+		var ok bool
+		if pos, ok = st.points[req.Point]; !ok {
+			s.mu.Unlock()
+			return errorf(http.StatusBadRequest, "unknown test point %q", req.Point)
+		}
+	default:
+		s.mu.Unlock()
+		return errorf(http.StatusBadRequest, "need point or pos")
+	}
+	// Snapshot the model, so later changes to the site don't affect
+	// this analysis.
+	m := st.model.Clone()
+	s.mu.Unlock()
+
+	// Identify the job by everything that affects its result. This
+	// hashes every mesh, so do it without holding s.mu.
+	key, err := m.AnalysisKey(start, end, increment, pos)
+	if err != nil {
+		return err
+	}
+	id := key[:16]
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if j, ok := s.jobs[id]; ok && (j.Status == "running" || j.Status == "done") {
+		return writeJSON(w, http.StatusOK, j)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &job{ID: id, Site: st.id, Status: "running", Started: time.Now(), cancel: cancel}
+	ctx = shade.WithProgress(ctx, func(p shade.Progress) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		j.Progress = &jobProgress{p.Traced, p.Total, p.ETA.Seconds()}
+	})
+	s.jobs[id] = j
+	go s.run(j, func() (*shade.IntensityOverTime, error) {
+		return m.IntensityOverPeriod(ctx, start, end, increment, pos)
+	})
+	jc := *j
+	return writeJSON(w, http.StatusAccepted, &jc)
+}
+
+// meshPath returns the file path of the mesh at slash-separated path p
+// relative to s.Root. It follows symbolic links, so it's an error if p
+// or any link it goes through leads outside s.Root.
+func (s *Server) meshPath(p string) (string, error) {
+	clean := path.Clean(p)
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", errorf(http.StatusBadRequest, "mesh path %q is outside the server root", p)
+	}
+	root, err := filepath.EvalSymlinks(s.Root)
+	if err != nil {
+		return "", err
+	}
+	full, err := filepath.EvalSymlinks(filepath.Join(root, filepath.FromSlash(clean)))
+	if err != nil {
+		return "", errorf(http.StatusBadRequest, "%s", err)
+	}
+	rel, err := filepath.Rel(root, full)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errorf(http.StatusBadRequest, "mesh path %q is outside the server root", p)
+	}
+	return full, nil
+}
+
+// maxFinishedJobs is the most finished jobs the server remembers. Beyond
+// this, it forgets the oldest ones and their results. Their sun light
+// series are still cached on disk, so starting the same analysis again
+// is fast.
+const maxFinishedJobs = 100
+
+// evictJobs forgets the oldest finished jobs beyond maxFinishedJobs.
+// s.mu must be held.
+func (s *Server) evictJobs() {
+	var finished []*job
+	for _, j := range s.jobs {
+		if j.Finished != nil {
+			finished = append(finished, j)
+		}
+	}
+	if len(finished) <= maxFinishedJobs {
+		return
+	}
+	sort.Slice(finished, func(i, k int) bool { return finished[i].Finished.Before(*finished[k].Finished) })
+	for _, j := range finished[:len(finished)-maxFinishedJobs] {
+		delete(s.jobs, j.ID)
+	}
+}
+
+// run runs the analysis f for job j.
+func (s *Server) run(j *job, f func() (*shade.IntensityOverTime, error)) {
+	var result *shade.IntensityOverTime
+	var err error
+	func() {
+		defer func() {
+			if e := recover(); e != nil {
+				err = fmt.Errorf("analysis failed: %v", e)
+			}
+		}()
+		result, err = f()
+	}()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j.cancel()
+	now := time.Now()
+	j.Finished = &now
+	switch {
+	case errors.Is(err, context.Canceled):
+		j.Status = "cancelled"
+	case err != nil:
+		j.Status, j.Error = "failed", err.Error()
+	default:
+		j.Status, j.result = "done", result
+	}
+	s.evictJobs()
+}
+
+// cancelJob cancels job id if it's running. The job finishes
+// cancelling in the background.
+func (s *Server) cancelJob(w http.ResponseWriter, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	if !ok {
+		return errorf(http.StatusNotFound, "unknown job %q", id)
+	}
+	if j.Status != "running" {
+		return errorf(http.StatusConflict, "job %s is %s", j.ID, j.Status)
+	}
+	j.cancel()
+	return writeJSON(w, http.StatusAccepted, j)
+}
+
+// apiTotals is a shade.SunTotals in the API.
+type apiTotals struct {
+	Start        string  `json:"start"`
+	End          string  `json:"end"`
+	Days         int     `json:"days"`
+	DirectHours  float64 `json:"direct_hours"`
+	FoliageHours float64 `json:"foliage_hours"`
+	Insolation   float64 `json:"insolation_kwh_m2"`
+}
+
+func newAPITotals(t shade.SunTotals) apiTotals {
+	return apiTotals{t.Start.Format("2006-01-02"), t.End.Format("2006-01-02"), t.Days, t.Direct.Hours(), t.Foliage.Hours(), t.Insolation}
+}
+
+func (s *Server) getResult(w http.ResponseWriter, o *shade.IntensityOverTime, name string) error {
+	switch name {
+	case "totals":
+		var res struct {
+			Total  apiTotals   `json:"total"`
+			Months []apiTotals `json:"months"`
+		}
+		res.Total = newAPITotals(o.Totals())
+		for _, t := range o.MonthlyTotals() {
+			res.Months = append(res.Months, newAPITotals(t))
+		}
+		return writeJSON(w, http.StatusOK, &res)
+
+	case "series":
+		w.Header().Set("Content-Type", "application/json")
+		return o.WriteSeries(w, shade.SeriesColumns)
+	}
+
+	ext := path.Ext(name)
+	opts := chart.DefaultPlotOptions
+	var plt chart.Drawer
+	var err error
+	switch strings.TrimSuffix(name, ext) {
+	case "heatmap":
+		plt, err = chart.HeatMap(o, nil, opts)
+	case "duration":
+		plt, err = chart.ShadeDuration(o, nil, opts)
+	case "hours":
+		plt, err = chart.MonthlySunHours(o, opts)
+	case "dli":
+		plt, err = chart.DLIPlot(o, opts)
+	default:
+		return errorf(http.StatusNotFound, "unknown result %q", name)
+	}
+	if err != nil {
+		return err
+	}
+	// Render to a buffer so we can still report errors.
+	var buf bytes.Buffer
+	if err := chart.EncodePlot(&buf, plt, ext, opts); err != nil {
+		return errorf(http.StatusNotFound, "%s", err)
+	}
+	w.Header().Set("Content-Type", mime.TypeByExtension(ext))
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+func readJSON(r *http.Request, v any) error {
+	dec := json.NewDecoder(io.LimitReader(r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return errorf(http.StatusBadRequest, "bad request: %s", err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
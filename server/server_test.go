@@ -0,0 +1,233 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aclements/shade"
+	"github.com/aclements/shade/geom"
+)
+
+// encodeSTL returns m as a binary STL file.
+func encodeSTL(m *geom.Mesh) []byte {
+	var buf bytes.Buffer
+	buf.Write(make([]byte, 80))
+	binary.Write(&buf, binary.LittleEndian, uint32(len(m.Tris)))
+	for _, tri := range m.Tris {
+		buf.Write(make([]byte, 12)) // Normal
+		for _, v := range tri {
+			for _, c := range m.Verts[v] {
+				binary.Write(&buf, binary.LittleEndian, math.Float32bits(float32(c)))
+			}
+		}
+		buf.Write(make([]byte, 2))
+	}
+	return buf.Bytes()
+}
+
+func TestServer(t *testing.T) {
+	// Analyses write to the cache in the current directory.
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	// A wall 10' tall and 100' long, 10' south of the origin, which
+	// shades the origin from the winter sun.
+	wall := &geom.Mesh{
+		Verts: [][3]float64{{-600, -120, 0}, {600, -120, 0}, {600, -120, 120}, {-600, -120, 120}},
+		Tris:  [][3]int{{0, 1, 2}, {0, 2, 3}},
+	}
+	if err := os.WriteFile(filepath.Join(dir, "wall.stl"), encodeSTL(wall), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(New(dir))
+	defer srv.Close()
+	do := func(method, path, contentType string, body []byte, wantCode int, out any) {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+path, bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", contentType)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != wantCode {
+			t.Fatalf("%s %s: got %s (%s), want %d", method, path, resp.Status, data, wantCode)
+		}
+		if out != nil {
+			if err := json.Unmarshal(data, out); err != nil {
+				t.Fatalf("%s %s: %s", method, path, err)
+			}
+		}
+	}
+	const js = "application/json"
+
+	var info siteInfo
+	do("POST", "/sites", js, []byte(`{"lat": 42.4, "lon": -71.2, "elevation": 200, "timezone": "America/New_York"}`), http.StatusCreated, &info)
+	site := "/sites/" + info.ID
+	do("POST", site+"/layers?name=upload", "model/stl", encodeSTL(wall), http.StatusCreated, nil)
+	do("POST", site+"/layers", js, []byte(`{"path": "wall.stl", "foliage": true}`), http.StatusCreated, nil)
+	do("POST", site+"/layers", js, []byte(`{"name": "escape", "path": "../wall.stl"}`), http.StatusBadRequest, nil)
+	outside := filepath.Join(t.TempDir(), "outside.stl")
+	if err := os.WriteFile(outside, encodeSTL(wall), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "link.stl")); err != nil {
+		t.Fatal(err)
+	}
+	do("POST", site+"/layers", js, []byte(`{"name": "escape", "path": "link.stl"}`), http.StatusBadRequest, nil)
+	do("POST", site+"/layers", js, []byte(`{"path": "wall.stl"}`), http.StatusConflict, nil)
+	do("PUT", site+"/points/patio", js, []byte(`[0, 0, 0]`), http.StatusOK, &info)
+	if len(info.Layers) != 2 || info.Layers[1] != (layerInfo{"wall", true, 2}) || info.Points["patio"] != [3]float64{} {
+		t.Fatalf("got site %+v", info)
+	}
+
+	var meshes []meshJSON
+	do("GET", site+"/meshes", "", nil, http.StatusOK, &meshes)
+	if len(meshes) != 2 || len(meshes[0].Verts) != 12 || len(meshes[0].Tris) != 6 || !meshes[1].Foliage {
+		t.Errorf("got meshes %+v", meshes)
+	}
+	var sun sunJSON
+	do("GET", site+"/sun?time=2022-12-21T12:00&pos=0,0,0", "", nil, http.StatusOK, &sun)
+	if sun.Light == nil || *sun.Light != 0 || sun.Dir[1] >= 0 || sun.Dir[2] <= 0 {
+		t.Errorf("got sun %+v, want shaded sun from the south", sun)
+	}
+	do("GET", site+"/sun?time=noon", "", nil, http.StatusBadRequest, nil)
+	resp, err := http.Get(srv.URL + site + "/view")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); resp.StatusCode != http.StatusOK || !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("view: got %s, %s", resp.Status, ct)
+	}
+
+	req := []byte(`{"point": "patio", "start": "2022-12-20", "end": "2022-12-22", "increment": "1h"}`)
+	var j job
+	do("POST", site+"/analyses", js, req, http.StatusAccepted, &j)
+	for deadline := time.Now().Add(time.Minute); j.Status == "running"; {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for analysis")
+		}
+		time.Sleep(10 * time.Millisecond)
+		do("GET", "/jobs/"+j.ID, "", nil, http.StatusOK, &j)
+	}
+	if j.Status != "done" {
+		t.Fatalf("job %s: %s", j.Status, j.Error)
+	}
+	if p := j.Progress; p == nil || p.Traced != 48 || p.Total != 48 {
+		t.Errorf("got progress %+v, want 48 of 48", p)
+	}
+	do("DELETE", "/jobs/"+j.ID, "", nil, http.StatusConflict, nil)
+	// Starting the same analysis again reuses the job.
+	var j2 job
+	do("POST", site+"/analyses", js, req, http.StatusOK, &j2)
+	if j2.ID != j.ID {
+		t.Errorf("repeated analysis got job %s, want %s", j2.ID, j.ID)
+	}
+
+	var totals struct {
+		Total apiTotals `json:"total"`
+	}
+	do("GET", "/jobs/"+j.ID+"/totals", "", nil, http.StatusOK, &totals)
+	if totals.Total.Days != 2 || totals.Total.DirectHours != 0 {
+		t.Errorf("got totals %+v, want 2 days and no direct sun", totals.Total)
+	}
+
+	resp, err = http.Get(srv.URL + "/jobs/" + j.ID + "/heatmap.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	png, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "image/png" || !strings.HasPrefix(string(png), "\x89PNG") {
+		t.Errorf("heatmap.png: got %s, %d bytes", ct, len(png))
+	}
+	do("GET", "/jobs/"+j.ID+"/heatmap.bmp", "", nil, http.StatusNotFound, nil)
+	do("GET", "/jobs/nope", "", nil, http.StatusNotFound, nil)
+}
+
+func TestCancelJob(t *testing.T) {
+	s := New(t.TempDir())
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	// A job that runs until it's cancelled.
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &job{ID: "j", Status: "running", cancel: cancel}
+	s.jobs[j.ID] = j
+	go s.run(j, func() (*shade.IntensityOverTime, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	req, err := http.NewRequest("DELETE", srv.URL+"/jobs/j", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("DELETE: got %s, want %d", resp.Status, http.StatusAccepted)
+	}
+	for deadline := time.Now().Add(time.Minute); ; time.Sleep(10 * time.Millisecond) {
+		s.mu.Lock()
+		status := j.Status
+		s.mu.Unlock()
+		if status == "cancelled" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job is %s, want cancelled", status)
+		}
+	}
+}
+
+func TestEvictJobs(t *testing.T) {
+	s := New(t.TempDir())
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < maxFinishedJobs+10; i++ {
+		finished := start.Add(time.Duration(i) * time.Minute)
+		id := fmt.Sprint("done", i)
+		s.jobs[id] = &job{ID: id, Status: "done", Finished: &finished}
+	}
+	s.jobs["running"] = &job{ID: "running", Status: "running"}
+	s.evictJobs()
+	if len(s.jobs) != maxFinishedJobs+1 {
+		t.Errorf("want %d jobs, got %d", maxFinishedJobs+1, len(s.jobs))
+	}
+	for _, id := range []string{"running", "done10", fmt.Sprint("done", maxFinishedJobs+9)} {
+		if s.jobs[id] == nil {
+			t.Errorf("job %s was evicted", id)
+		}
+	}
+	if s.jobs["done9"] != nil {
+		t.Errorf("oldest jobs weren't evicted")
+	}
+}
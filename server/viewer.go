@@ -0,0 +1,101 @@
+package server
+
+import (
+	_ "embed"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aclements/shade"
+	"github.com/aclements/shade/solar"
+)
+
+//go:embed viewer.html
+var viewerHTML []byte
+
+// getViewer serves a 3D viewer of a site for picking test points. The
+// viewer shows the site's layers with the sun and shadows at a chosen
+// time, places a test point where the user clicks, and can start an
+// analysis of that point and show its heat map. It uses WebGL and has
+// no external assets, so it works offline.
+func (s *Server) getViewer(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, err := w.Write(viewerHTML)
+	return err
+}
+
+// meshJSON is a layer's mesh in the API. Verts and Tris are flattened
+// to make them compact and easy to load into WebGL buffers.
+type meshJSON struct {
+	Name     string    `json:"name"`
+	Foliage  bool      `json:"foliage"`
+	Disabled bool      `json:"disabled"`
+	Verts    []float32 `json:"verts"`
+	Tris     []int     `json:"tris"`
+}
+
+func (s *Server) getMeshes(w http.ResponseWriter, st *site) error {
+	s.mu.Lock()
+	m := st.model.Clone()
+	s.mu.Unlock()
+
+	out := []meshJSON{}
+	for _, l := range m.Layers() {
+		mj := meshJSON{Name: l.Name, Foliage: l.Foliage, Disabled: l.Disabled}
+		mj.Verts = make([]float32, 0, 3*len(l.Mesh.Verts))
+		for _, v := range l.Mesh.Verts {
+			mj.Verts = append(mj.Verts, float32(v[0]), float32(v[1]), float32(v[2]))
+		}
+		mj.Tris = make([]int, 0, 3*len(l.Mesh.Tris))
+		for _, t := range l.Mesh.Tris {
+			mj.Tris = append(mj.Tris, t[0], t[1], t[2])
+		}
+		out = append(out, mj)
+	}
+	return writeJSON(w, http.StatusOK, out)
+}
+
+// sunJSON is the sun position, and optionally the sun light at a test
+// position, in the API.
+type sunJSON struct {
+	Time     time.Time  `json:"time"`
+	Altitude float64    `json:"altitude"`
+	Azimuth  float64    `json:"azimuth"`
+	Dir      [3]float64 `json:"dir"` // Unit vector toward the sun
+
+	Light   *float64 `json:"light,omitempty"`
+	Foliage bool     `json:"foliage,omitempty"`
+}
+
+func (s *Server) getSun(w http.ResponseWriter, r *http.Request, st *site) error {
+	q := r.URL.Query()
+	t, err := time.ParseInLocation("2006-01-02T15:04", q.Get("time"), st.loc)
+	if err != nil {
+		return errorf(http.StatusBadRequest, "bad time: %s", err)
+	}
+
+	s.mu.Lock()
+	m := st.model.Clone()
+	s.mu.Unlock()
+
+	sun := solar.GetSunPos(t, m.Latitude(), m.Longitude())
+	ray := sun.Ray([3]float64{})
+	out := sunJSON{Time: t, Altitude: sun.Altitude, Azimuth: sun.Azimuth, Dir: [3]float64{ray.Dir.X, ray.Dir.Y, ray.Dir.Z}}
+
+	if p := q.Get("pos"); p != "" {
+		var pos [3]float64
+		fields := strings.Split(p, ",")
+		if len(fields) != 3 {
+			return errorf(http.StatusBadRequest, "pos must be x,y,z")
+		}
+		for i, f := range fields {
+			if pos[i], err = strconv.ParseFloat(f, 64); err != nil {
+				return errorf(http.StatusBadRequest, "bad pos: %s", err)
+			}
+		}
+		light := shade.TraceSun(m.ActiveLayers(), sun, pos)
+		out.Light, out.Foliage = &light.Light, light.Foliage
+	}
+	return writeJSON(w, http.StatusOK, &out)
+}
@@ -0,0 +1,48 @@
+package shade
+
+import "github.com/aclements/shade/solar"
+
+// A Skyline is the horizon of obstructions around a test point.
+type Skyline struct {
+	// Azimuth is the azimuth of each sample, in degrees.
+	Azimuth []float64
+
+	// Building and Foliage are the altitude, in degrees, of the top of
+	// building and foliage obstructions at each azimuth. If there is no
+	// obstruction, the altitude is 0.
+	Building, Foliage []float64
+}
+
+// Skyline computes the obstruction skyline around testPos by casting
+// rays through the model's layers every step degrees of azimuth and
+// altitude.
+func (m *ShadeModel) Skyline(testPos [3]float64, step float64) *Skyline {
+	layers := m.ActiveLayers()
+	s := new(Skyline)
+	for az := 0.0; az < 360; az += step {
+		building, foliage := 0.0, 0.0
+		haveBuilding, haveFoliage := false, false
+		// Scan down from the zenith, stopping when we've found the top
+		// of both kinds of obstruction.
+		for alt := 90.0; alt >= 0 && !(haveBuilding && haveFoliage); alt -= step {
+			ray := solar.SunPos{Altitude: alt, Azimuth: az}.Ray(testPos)
+			for _, l := range layers {
+				if (l.Foliage && haveFoliage) || (!l.Foliage && haveBuilding) {
+					continue
+				}
+				if _, hit := ray.IntersectMesh(l.Mesh); !hit {
+					continue
+				}
+				if l.Foliage {
+					foliage, haveFoliage = alt, true
+				} else {
+					building, haveBuilding = alt, true
+				}
+			}
+		}
+		s.Azimuth = append(s.Azimuth, az)
+		s.Building = append(s.Building, building)
+		s.Foliage = append(s.Foliage, foliage)
+	}
+	return s
+}
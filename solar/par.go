@@ -0,0 +1,39 @@
+package solar
+
+import "math"
+
+const (
+	// parFraction is the fraction of the energy of global solar
+	// radiation that is photosynthetically active radiation (PAR,
+	// 400–700 nm).
+	parFraction = 0.45
+
+	// foliageParFraction is the fraction of the energy of sunlight
+	// transmitted through foliage that is PAR. Leaves absorb most PAR
+	// and transmit most near-infrared, so the light that gets through
+	// is depleted in PAR compared to open sunlight.
+	foliageParFraction = 0.2
+
+	// parPhotonsPerJoule converts PAR from sunlight from W/m² to
+	// µmol/m²/s.
+	parPhotonsPerJoule = 4.57
+)
+
+// PPFD computes the photosynthetic photon flux density of the sun at
+// this position on a horizontal surface, in µmol/m²/s. If the sun is
+// filtered by foliage, this accounts for the foliage absorbing more PAR
+// than other radiation.
+func (p SunLight) PPFD(elevationFeet float64) (micromolesPerSquareMeterSecond float64) {
+	direct := p.DirectIntensity(elevationFeet)
+	beamPAR := parFraction
+	if p.Foliage {
+		beamPAR = foliageParFraction
+	}
+	// As in GlobalIntensity, diffuse radiation is ~10% of direct
+	// radiation. Unlike GlobalIntensity, we project the direct
+	// radiation on to a horizontal surface, since that's how DLI is
+	// conventionally measured.
+	horiz := math.Sin(p.Altitude * (math.Pi / 180))
+	par := 0.1*direct*parFraction + p.Light*direct*beamPAR*horiz
+	return par * parPhotonsPerJoule
+}
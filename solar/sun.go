@@ -1,13 +1,17 @@
-package main
+// Package solar computes the position of the sun and the intensity of
+// sun light.
+package solar
 
 import (
 	"math"
 	"time"
 
+	"github.com/aclements/shade/geom"
 	"github.com/sixdouglas/suncalc"
 	"gonum.org/v1/gonum/spatial/r3"
 )
 
+// A SunPos is the position of the sun in the sky at time T.
 type SunPos struct {
 	T time.Time
 
@@ -36,11 +40,12 @@ func GetSunPos(t time.Time, latitude, longitude float64) SunPos {
 	return SunPos{t, p.Altitude * rad2deg, p.Azimuth*rad2deg + 180}
 }
 
-func (p SunPos) Ray(origin [3]float64) Ray {
+// Ray returns the ray from origin toward the sun.
+func (p SunPos) Ray(origin [3]float64) geom.Ray {
 	const deg2rad = math.Pi / 180
 	al := p.Altitude * deg2rad
 	az := p.Azimuth * deg2rad
-	return Ray{
+	return geom.Ray{
 		Origin: r3.Vec{X: origin[0], Y: origin[1], Z: origin[2]},
 		Dir: r3.Unit(r3.Vec{
 			X: math.Sin(az) * math.Cos(al),
@@ -50,6 +55,7 @@ func (p SunPos) Ray(origin [3]float64) Ray {
 	}
 }
 
+// A SunLight is the sun light reaching a point at the time of SunPos.
 type SunLight struct {
 	SunPos
 
@@ -61,6 +67,13 @@ type SunLight struct {
 // solar flux, aka insolation) at this position on a plane perpendicular
 // to the sun, in W/m².
 func (p SunLight) GlobalIntensity(elevationFeet float64) (wattsPerSquareMeter float64) {
+	// Diffuse radiation is ~10% of direct radiation.
+	return (0.1 + p.Light) * p.DirectIntensity(elevationFeet)
+}
+
+// DirectIntensity computes the unobstructed direct radiation of the sun
+// at this position on a plane perpendicular to the sun, in W/m².
+func (p SunPos) DirectIntensity(elevationFeet float64) (wattsPerSquareMeter float64) {
 	// This is based on https://www.pveducation.org/pvcdrom/properties-of-sunlight/air-mass
 	if p.Altitude < 0 {
 		return 0
@@ -84,47 +97,5 @@ func (p SunLight) GlobalIntensity(elevationFeet float64) (wattsPerSquareMeter fl
 	// Addison Wesley Publishing Co., 1976.
 	h := elevationFeet * 0.0003048 // To kilometers
 	a := 0.14
-	iDirect := 1353 * ((1-a*h)*math.Pow(0.7, math.Pow(airMass, 0.678)) + a*h)
-
-	// Diffuse radiation is ~10% of direct radiation.
-	return (0.1 + p.Light) * iDirect
-}
-
-func (m *ShadeModel) computeSunLight(testPos [3]float64, times []time.Time) []SunLight {
-	// TODO: This could be much more efficient. Do traces in parallel
-	// and since I only care about hit tests for this, not exact
-	// intersection point, add a fast path that caches the last triangle
-	// intersection and retests just that triangle on the next ray.
-	light := make([]SunLight, len(times))
-	for i, t := range times {
-		out := &light[i]
-		sunPos := GetSunPos(t, m.lat, m.lon)
-		out.SunPos = sunPos
-		if sunPos.Altitude < 0 {
-			out.Light = 0
-			out.Foliage = false
-			continue
-		}
-
-		sunRay := sunPos.Ray(testPos)
-		light := 1.0
-		building, foliage := false, false
-		for _, l := range m.layers {
-			tRay, hit := sunRay.IntersectMesh(l.mesh)
-			_ = tRay
-			if hit && light != 0 {
-				light *= l.transmissivity(t)
-			}
-			if hit {
-				if l.foliage {
-					foliage = true
-				} else {
-					building = true
-				}
-			}
-		}
-		out.Light = light
-		out.Foliage = foliage && !building
-	}
-	return light
+	return 1353 * ((1-a*h)*math.Pow(0.7, math.Pow(airMass, 0.678)) + a*h)
 }
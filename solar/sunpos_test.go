@@ -1,4 +1,4 @@
-package main
+package solar
 
 import "testing"
 
@@ -26,3 +26,22 @@ func TestGlobalIntensity(t *testing.T) {
 	p = mkPos(0)
 	assertBetween(t, "GlobalIntensity at 0°", p.GlobalIntensity(0), 22.4, 22.5)
 }
+
+func TestPPFD(t *testing.T) {
+	// Full sun is roughly 2000 µmol/m²/s.
+	p := SunLight{Light: 1, SunPos: SunPos{Altitude: 90}}
+	assertBetween(t, "PPFD at 90°", p.PPFD(0), 2000, 2300)
+
+	// Foliage should cut PAR more than it cuts total radiation.
+	f := SunLight{Light: 0.5, Foliage: true, SunPos: SunPos{Altitude: 90}}
+	ppfdRatio := f.PPFD(0) / p.PPFD(0)
+	globalRatio := f.GlobalIntensity(0) / p.GlobalIntensity(0)
+	if ppfdRatio >= globalRatio {
+		t.Errorf("foliage PPFD ratio %v is not less than global intensity ratio %v", ppfdRatio, globalRatio)
+	}
+
+	p = SunLight{Light: 1, SunPos: SunPos{Altitude: -1}}
+	if got := p.PPFD(0); got != 0 {
+		t.Errorf("got PPFD below horizon = %v, want 0", got)
+	}
+}
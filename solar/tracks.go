@@ -0,0 +1,54 @@
+package solar
+
+import "time"
+
+// Tracks is the sun's track across the sky at important times of a
+// year.
+type Tracks struct {
+	Months                  [][]SunPos // Tracks on the 21st of each month
+	Summer, Winter, Equinox []SunPos
+	Hours                   [][]SunPos // Analemmas for each hour, in standard time
+}
+
+// GetTracks returns the sun's tracks over year at the given latitude
+// and longitude, with days and hours in loc.
+func GetTracks(year int, loc *time.Location, latitude, longitude float64) *Tracks {
+	s := new(Tracks)
+	track := func(month time.Month, day int) []SunPos {
+		var track []SunPos
+		t := time.Date(year, month, day, 0, 0, 0, 0, loc)
+		for end := t.AddDate(0, 0, 1); t.Before(end); t = t.Add(5 * time.Minute) {
+			track = append(track, GetSunPos(t, latitude, longitude))
+		}
+		return track
+	}
+	for month := time.January; month <= time.December; month++ {
+		s.Months = append(s.Months, track(month, 21))
+	}
+	s.Summer, s.Winter, s.Equinox = track(time.June, 21), track(time.December, 21), track(time.March, 20)
+	// Compute analemmas for each hour, connecting the sun's position
+	// at that hour on the 21st of each month. We use standard time
+	// (ignoring DST) so these are smooth curves.
+	std := StandardTime(year, loc)
+	for hour := 0; hour < 24; hour++ {
+		var a []SunPos
+		for month := time.January; month <= time.December; month++ {
+			a = append(a, GetSunPos(time.Date(year, month, 21, hour, 0, 0, 0, std), latitude, longitude))
+		}
+		a = append(a, a[0])
+		s.Hours = append(s.Hours, a)
+	}
+	return s
+}
+
+// StandardTime returns the time zone loc during year without daylight
+// saving time.
+func StandardTime(year int, loc *time.Location) *time.Location {
+	// Daylight saving time is ahead of standard time, so take whichever
+	// of January or July is behind.
+	name, offset := time.Date(year, 1, 1, 0, 0, 0, 0, loc).Zone()
+	if n, o := time.Date(year, 7, 1, 0, 0, 0, 0, loc).Zone(); o < offset {
+		name, offset = n, o
+	}
+	return time.FixedZone(name, offset)
+}
@@ -0,0 +1,28 @@
+package solar
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStandardTime(t *testing.T) {
+	// StandardTime must use the given zone, not the process's.
+	for _, test := range []struct {
+		zone   string
+		offset int
+	}{
+		{"America/New_York", -5 * 3600},
+		{"Europe/Berlin", 1 * 3600},
+		{"Australia/Sydney", 10 * 3600}, // DST is in January
+		{"UTC", 0},
+	} {
+		loc, err := time.LoadLocation(test.zone)
+		if err != nil {
+			t.Skip(err)
+		}
+		_, offset := time.Date(2022, 1, 1, 0, 0, 0, 0, StandardTime(2022, loc)).Zone()
+		if offset != test.offset {
+			t.Errorf("%s: want offset %d, got %d", test.zone, test.offset, offset)
+		}
+	}
+}
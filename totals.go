@@ -0,0 +1,193 @@
+package shade
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aclements/shade/solar"
+)
+
+// SunTotals summarizes the sun exposure over a period of time.
+type SunTotals struct {
+	// Start and End are the bounds of this period. End is exclusive.
+	Start, End time.Time
+
+	// Days is the number of calendar days in this period.
+	Days int
+
+	// Direct is the time spent in direct sun.
+	Direct time.Duration
+
+	// Foliage is the time spent in sun that is filtered only by
+	// foliage.
+	Foliage time.Duration
+
+	// Insolation is the total solar energy received, in kWh/m².
+	Insolation float64
+}
+
+// Totals returns the sun exposure totals over the entire period of o.
+func (o *IntensityOverTime) Totals() SunTotals {
+	var t SunTotals
+	for _, day := range o.byDay() {
+		t.addDay(day, o)
+	}
+	return t
+}
+
+// DailyTotals returns the sun exposure totals for each day of o.
+func (o *IntensityOverTime) DailyTotals() []SunTotals {
+	days := o.byDay()
+	totals := make([]SunTotals, len(days))
+	for i, day := range days {
+		totals[i].addDay(day, o)
+	}
+	return totals
+}
+
+// MonthlyTotals returns the sun exposure totals for each calendar month
+// of o.
+func (o *IntensityOverTime) MonthlyTotals() []SunTotals {
+	var totals []SunTotals
+	for _, day := range o.byDay() {
+		if len(totals) == 0 || day[0].T.Month() != totals[len(totals)-1].Start.Month() {
+			totals = append(totals, SunTotals{})
+		}
+		totals[len(totals)-1].addDay(day, o)
+	}
+	return totals
+}
+
+// addDay adds the time steps in day, which must all be on the same
+// calendar day, to t and extends t's period to include that day.
+func (t *SunTotals) addDay(day []solar.SunLight, o *IntensityOverTime) {
+	y, m, d := day[0].T.Date()
+	start := time.Date(y, m, d, 0, 0, 0, 0, day[0].T.Location())
+	if t.Days == 0 {
+		t.Start = start
+	}
+	t.End = start.AddDate(0, 0, 1)
+	t.Days++
+	for _, sun := range day {
+		t.add(sun, o.increment, o.elevationFeet)
+	}
+}
+
+func (t *SunTotals) add(sun solar.SunLight, increment time.Duration, elevationFeet float64) {
+	switch SunLevel(sun) {
+	case LevelDirect:
+		t.Direct += increment
+	case LevelFoliage:
+		t.Foliage += increment
+	}
+	t.Insolation += sun.GlobalIntensity(elevationFeet) * increment.Hours() / 1000
+}
+
+// DirectPerDay returns the average time in direct sun per day.
+func (t SunTotals) DirectPerDay() time.Duration {
+	return t.perDay(t.Direct)
+}
+
+// FoliagePerDay returns the average time in foliage-filtered sun per
+// day.
+func (t SunTotals) FoliagePerDay() time.Duration {
+	return t.perDay(t.Foliage)
+}
+
+func (t SunTotals) perDay(d time.Duration) time.Duration {
+	if t.Days == 0 {
+		return 0
+	}
+	return d / time.Duration(t.Days)
+}
+
+// Label returns a short name for t's period, such as a date or month.
+func (t SunTotals) Label() string {
+	switch {
+	case t.Days == 1:
+		return t.Start.Format("2006-01-02")
+	case t.Start.Day() == 1 && t.End.Equal(t.Start.AddDate(0, 1, 0)):
+		return t.Start.Format("Jan 2006")
+	}
+	return t.Start.Format("2006-01-02") + " – " + t.End.AddDate(0, 0, -1).Format("2006-01-02")
+}
+
+// WriteTotalsTable writes a human-readable table of totals to w.
+func WriteTotalsTable(w io.Writer, totals []SunTotals) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "Period\tDirect sun (h)\tper day\tFoliage sun (h)\tper day\tInsolation (kWh/m²)\tper day\t\n")
+	for _, t := range totals {
+		fmt.Fprintf(tw, "%s\t%.1f\t%.1f\t%.1f\t%.1f\t%.1f\t%.2f\t\n", t.Label(),
+			t.Direct.Hours(), t.DirectPerDay().Hours(),
+			t.Foliage.Hours(), t.FoliagePerDay().Hours(),
+			t.Insolation, t.Insolation/float64(t.Days))
+	}
+	return tw.Flush()
+}
+
+// WriteTotalsCSV writes totals to w in CSV format.
+func WriteTotalsCSV(w io.Writer, totals []SunTotals) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"start", "end", "days", "direct_hours", "foliage_hours", "insolation_kwh_m2"})
+	ff := func(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+	for _, t := range totals {
+		cw.Write([]string{
+			t.Start.Format("2006-01-02"),
+			t.End.Format("2006-01-02"),
+			strconv.Itoa(t.Days),
+			ff(t.Direct.Hours()),
+			ff(t.Foliage.Hours()),
+			ff(t.Insolation),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// byDay splits the time steps of o into calendar days. Every day has
+// at least one time step.
+func (o *IntensityOverTime) byDay() [][]solar.SunLight {
+	if len(o.sunPos) == 0 {
+		return nil
+	}
+	var days [][]solar.SunLight
+	last := 0
+	for i := range o.sunPos {
+		if i > 0 && !sameDay(o.sunPos[i].T, o.sunPos[last].T) {
+			days = append(days, o.sunPos[last:i])
+			last = i
+		}
+	}
+	return append(days, o.sunPos[last:])
+}
+
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// Levels of sun exposure returned by SunLevel.
+const (
+	LevelShade   = 0   // Shade or darkness
+	LevelFoliage = 0.5 // Sun filtered only by foliage
+	LevelDirect  = 1   // Direct sun
+)
+
+// SunLevel classifies the sun exposure of p as one of LevelShade,
+// LevelFoliage, or LevelDirect.
+func SunLevel(p solar.SunLight) float64 {
+	switch {
+	case p.Altitude < 0:
+		return LevelShade
+	case p.Foliage:
+		return LevelFoliage
+	case p.Light >= 0.05:
+		return LevelDirect
+	}
+	return LevelShade
+}
@@ -0,0 +1,66 @@
+package shade
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aclements/shade/solar"
+)
+
+func TestTotals(t *testing.T) {
+	// Two days at the end of one month and one day at the start of the
+	// next. The sun is up from 8:00 to 15:00, and blocked by foliage
+	// from 12:00 to 13:00.
+	o := &IntensityOverTime{increment: time.Hour}
+	start := time.Date(2022, 5, 30, 0, 0, 0, 0, time.UTC)
+	for h := 0; h < 3*24; h++ {
+		sun := solar.SunLight{SunPos: solar.SunPos{T: start.Add(time.Duration(h) * time.Hour), Altitude: -10}}
+		if hod := h % 24; 8 <= hod && hod < 16 {
+			sun.Altitude, sun.Light = 45, 1
+			if hod == 12 || hod == 13 {
+				sun.Light, sun.Foliage = 0.05, true
+			}
+		}
+		o.sunPos = append(o.sunPos, sun)
+	}
+
+	daily := o.DailyTotals()
+	if len(daily) != 3 {
+		t.Fatalf("got %d days, want 3", len(daily))
+	}
+	for _, d := range daily {
+		if d.Days != 1 || d.Direct != 6*time.Hour || d.Foliage != 2*time.Hour {
+			t.Errorf("%s: got %d days, %s direct, %s foliage; want 1 day, 6h direct, 2h foliage", d.Label(), d.Days, d.Direct, d.Foliage)
+		}
+	}
+
+	monthly := o.MonthlyTotals()
+	if len(monthly) != 2 {
+		t.Fatalf("got %d months, want 2", len(monthly))
+	}
+	if m := monthly[0]; m.Days != 2 || m.Direct != 12*time.Hour || m.DirectPerDay() != 6*time.Hour {
+		t.Errorf("May: got %d days, %s direct, %s/day; want 2 days, 12h direct, 6h/day", m.Days, m.Direct, m.DirectPerDay())
+	}
+	if m := monthly[1]; !m.Start.Equal(time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)) || m.Days != 1 {
+		t.Errorf("June: got start %s and %d days; want 2022-06-01 and 1 day", m.Start, m.Days)
+	}
+}
+
+func TestTotalsEmpty(t *testing.T) {
+	o := &IntensityOverTime{increment: time.Minute}
+	if got := o.Totals(); got != (SunTotals{}) {
+		t.Errorf("Totals: got %+v, want zero totals", got)
+	}
+	if got := o.DailyTotals(); len(got) != 0 {
+		t.Errorf("DailyTotals: got %d days, want 0", len(got))
+	}
+	if got := o.MonthlyTotals(); len(got) != 0 {
+		t.Errorf("MonthlyTotals: got %d months, want 0", len(got))
+	}
+	if got := o.DailyLightIntegral(); len(got) != 0 {
+		t.Errorf("DailyLightIntegral: got %d days, want 0", len(got))
+	}
+	if got := (&PVPanel{Tilt: 30, Azimuth: 180}).Yield(o); len(got.Monthly) != 0 || got.Annual != (PVEnergy{}) {
+		t.Errorf("Yield: got %+v, want zero yield", got)
+	}
+}